@@ -1,46 +1,82 @@
 package config
 
 import (
-	"fmt"
 	"log"
 
-	"github.com/jmoiron/jsonq"
-
+	"bytes"
 	"encoding/json"
 	"os"
 	"strings"
 )
 
 type Configurer interface {
-	// Load reads in a configuration as a function of GO_ENV and the validates it. For GO_ENV=development, we'll load
-	// the production configuration first and then layer development on top as overrides.
+	// Load reads in a configuration as a function of GO_ENV and the validates it. Each configuration file can name the
+	// environment it's layered on top of with an "extends" key (development extends production by default).
 	Load() error
 
 	// LogSummary prints out details about the configuration.
 	LogSummary()
 
 	// ReadProperty lets properties be read from the config using a query path
-	// (e.g. "objectA.arrayB.0"). If any errors occur, we return an empty string.
+	// (e.g. "objectA.arrayB.0"). Objects and arrays are returned as json. If any
+	// errors occur, we return an empty string. The -property flag reads config
+	// fields directly and only calls ReadProperty for query paths that don't
+	// match a field, so implementations can serve computed properties.
 	ReadProperty(queryPath string) (string, error)
 
 	// Validates the configuration.
 	Validate() error
 }
 
+// ConfigurerFactory can be implemented by configurers that are given defaults
+// before they're loaded, so reloads start from those defaults rather than a
+// zero value.
+type ConfigurerFactory interface {
+	// NewConfigurer returns a new, not yet loaded, instance of the configurer
+	// with its defaults set.
+	NewConfigurer() Configurer
+}
+
 type Config struct {
 	Configurer     `json:"-"`
-	Env            string `json:"env"`
-	LogFile        string `json:"logFile"`
-	VerboseLogging bool   `json:"verboseLogging"`
+	Env            string `json:"env" description:"Name of the environment (e.g. production or development)."`
+	LogFile        string `json:"logFile" description:"File to write logs to (stderr when unset)."`
+	LogFormat      string `json:"logFormat" validate:"oneof=text json" description:"Log output format."` // "text" (default) or "json"
+	VerboseLogging bool   `json:"verboseLogging" description:"Enables debug logging."`
+
+	// Log rotation (only applies when LogFile is set). LogMaxSize is in
+	// megabytes, LogMaxAge is the number of days to retain rotated files,
+	// LogMaxBackups is the number of rotated files to retain and
+	// LogRotateHours forces a rotation every so many hours. Size based
+	// rotation can't be disabled, a zero LogMaxSize uses the default of 100
+	// megabytes; zero values of the others disable the respective limit.
+	LogMaxSize     int  `json:"logMaxSize" validate:"min=0" description:"Megabytes a log file grows to before rotating (defaults to 100)."`
+	LogMaxAge      int  `json:"logMaxAge" validate:"min=0" description:"Days to retain rotated log files."`
+	LogMaxBackups  int  `json:"logMaxBackups" validate:"min=0" description:"Number of rotated log files to retain."`
+	LogRotateHours int  `json:"logRotateHours" validate:"min=0" description:"Forces a log rotation every so many hours."`
+	LogCompress    bool `json:"logCompress" description:"Gzips rotated log files."`
+
+	loadedFiles []string
 }
 
 func (c *Config) Load() error {
-	return LoadConfig(c)
+	files, err := LoadConfigFiles(c, DefaultLoadOptions)
+	c.loadedFiles = files
+	return err
+}
+
+// LoadedFiles returns the configuration files that were loaded, in the order
+// they were layered.
+func (c *Config) LoadedFiles() []string {
+	return c.loadedFiles
 }
 
 func (c *Config) LogSummary() {
 	log.Printf("platform configuration summary")
+	log.Printf(" config files: ..... %v", strings.Join(c.loadedFiles, ", "))
 	log.Printf(" environment: ...... %v", c.Env)
+	log.Printf(" log file: ......... %v", c.LogFile)
+	log.Printf(" log format: ....... %v", c.LogFormat)
 	log.Printf(" verbose logging: .. %v", c.VerboseLogging)
 }
 
@@ -52,85 +88,127 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// LoadOptions control how configuration is loaded by LoadConfig.
+type LoadOptions struct {
+	// EnvPrefix is prepended to environment variable overrides, e.g. the
+	// "PLATFORM" prefix maps PLATFORM_HTTP_PORT onto the "http.port" field.
+	// An empty prefix maps HTTP_PORT instead.
+	EnvPrefix string
+
+	// Layers (optional) is an explicit, ordered list of environments to layer
+	// (e.g. []string{"production", "staging"}), the last taking precedence.
+	// When empty, layers are resolved from each file's "extends" key.
+	Layers []string
+
+	// SecretResolvers resolve "${<scheme>:<reference>}" references in string
+	// fields (including strings in slices and maps) by scheme. The "env" and
+	// "file" schemes are built in (and can be overridden here).
+	SecretResolvers map[string]SecretResolver
+
+	// Strict controls whether unknown (e.g. misspelled) keys in configuration
+	// files are rejected. By default, they're only rejected in production.
+	Strict StrictMode
+
+	// SharedConfigurers are the other configurers decoded from the same files.
+	// In strict mode a key is only unknown if none of them has it either.
+	SharedConfigurers []Configurer
+
+	// SearchDirs are the directories searched (in order) for each layer's
+	// configuration file. Defaults to the working directory.
+	SearchDirs []string
+}
+
+// DefaultLoadOptions are used by LoadConfig. Services typically adjust these
+// through ServiceOptions before any configuration is loaded.
+var DefaultLoadOptions = &LoadOptions{
+	EnvPrefix: "PLATFORM",
+}
+
 func LoadConfig(c Configurer) error {
+	return LoadConfigWithOptions(c, DefaultLoadOptions)
+}
+
+// LoadConfigWithOptions loads the configuration files, applies environment
+// variable overrides and then validates the configuration.
+func LoadConfigWithOptions(c Configurer, options *LoadOptions) error {
+	_, err := LoadConfigFiles(c, options)
+	return err
+}
+
+// LoadConfigFiles is LoadConfigWithOptions but also returns the configuration
+// files that were loaded, in the order they were layered.
+func LoadConfigFiles(c Configurer, options *LoadOptions) ([]string, error) {
 	// Grab the environmental variable
 	env := os.Getenv("GO_ENV")
 	if len(env) < 1 {
 		env = "development"
 	}
 
-	// For development, layer in prod first (so dev can just be overrides).
-	if env == "development" {
-		err := decodeJson("config-production.json", c)
+	// Decode each layer on top of the previous ones (so layers can just be
+	// overrides).
+	layers, err := resolveLayers(env, options)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	isStrict := options.Strict.isStrict(env)
+	for _, l := range layers {
+		if isStrict {
+			err = checkUnknownKeys(l, append([]Configurer{c}, options.SharedConfigurers...))
+			if err != nil {
+				return files, err
+			}
+		}
+		err = decodeLayer(l, c)
 		if err != nil {
-			return err
+			return files, err
 		}
+		files = append(files, l.filename)
 	}
 
-	err := decodeJson(fmt.Sprintf("config-%s.json", env), c)
+	// Environment variables override anything from the files
+	err = applyEnvOverrides(c, options.EnvPrefix)
 	if err != nil {
-		return err
+		return files, err
 	}
 
-	// Validate the config
-	err = c.Validate()
+	// Resolve secret references now that all values are in place
+	err = resolveSecrets(c, options.SecretResolvers)
 	if err != nil {
-		return err
+		return files, err
 	}
 
-	return err
-}
-
-func ReadConfigProperty(c Configurer, queryPath string) (string, error) {
-	data := map[string]interface{}{}
-
-	// Convert the current config struct into a format jsonq can query
-	b, err := json.Marshal(c)
+	// Validate the config, first against its struct tags and then any bespoke
+	// validation
+	err = ValidateStruct(c)
 	if err != nil {
-		return "", err
+		return files, err
 	}
-	decoder := json.NewDecoder(strings.NewReader(string(b)))
-	decoder.Decode(&data)
-
-	// Break the query path into a string array that jsonq can work with
-	jq := jsonq.NewQuery(data)
-
-	// We only support int, float, bool, and string
-	var jqErr error
-	intValue, jqErr := jq.Int(strings.Split(queryPath, ".")...)
-	if jqErr == nil {
-		return fmt.Sprintf("%d", intValue), nil
-	}
-	floatValue, jqErr := jq.Float(strings.Split(queryPath, ".")...)
-	if jqErr == nil {
-		return fmt.Sprintf("%f", floatValue), nil
-	}
-	boolValue, jqErr := jq.Bool(strings.Split(queryPath, ".")...)
-	if jqErr == nil {
-		return fmt.Sprintf("%t", boolValue), nil // "true" or "false"
-	}
-	stringValue, jqErr := jq.String(strings.Split(queryPath, ".")...)
-	if jqErr == nil {
-		return stringValue, nil
+	err = c.Validate()
+	if err != nil {
+		return files, err
 	}
 
-	return "", fmt.Errorf("unable to find value for %s (are you sure it exists and is bool, float, int, or string?): "+
-		"%s", queryPath, jqErr)
+	return files, nil
 }
 
-func decodeJson(filename string, s interface{}) error {
-	// Open the file
-	file, err := os.Open(filename)
+// ReadConfigProperty reads a property as plain text (see ReadConfigValue and
+// FormatRawValue), e.g. 8080, 0.25, true, a string or json for objects and
+// arrays.
+func ReadConfigProperty(c Configurer, queryPath string) (string, error) {
+	value, err := ReadConfigValue(c, queryPath)
 	if err != nil {
-		return err
+		return "", err
 	}
-	defer file.Close()
+	return FormatRawValue(value)
+}
 
+func decodeLayer(l *layer, s interface{}) error {
 	// Decode the json using given interface
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(s)
+	decoder := json.NewDecoder(bytes.NewReader(l.data))
+	err := decoder.Decode(s)
 	if err != nil {
-		return err
+		return l.describeDecodeError(err)
 	}
 
 	return nil
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Cors configures cross-origin resource sharing for an http server. Each
+// entry in Routers overrides the policy for requests under a path prefix
+// (e.g. "/api/public"), the longest matching prefix wins. Unset router fields
+// inherit from the top-level policy.
+type Cors struct {
+	CorsPolicy
+	Routers map[string]CorsPolicy `json:"routers" description:"CORS policy overrides keyed by path prefix."`
+}
+
+// CorsPolicy is a CORS policy. Zero values use the defaults: any origin, the
+// OPTIONS, DELETE, GET, HEAD, POST and PUT methods and the Authorization and
+// Content-Type headers.
+type CorsPolicy struct {
+	// Disable and AllowCredentials are pointers so router overrides can turn
+	// them off (false) when the top-level policy sets them, nil inherits.
+	Disable *bool `json:"disable" description:"Disables CORS handling (false re-enables it for a router)."`
+
+	// AllowedOrigins are origins (e.g. "https://app.example.com") that may
+	// make cross-origin requests. A leading "*." in the host matches any
+	// subdomain (e.g. "https://*.example.com") and "*" matches any origin.
+	AllowedOrigins   []string `json:"allowedOrigins" description:"Origins allowed to make requests, \"https://*.example.com\" matches subdomains and \"*\" any origin."`
+	AllowedMethods   []string `json:"allowedMethods" description:"Methods allowed in cross-origin requests."`
+	AllowedHeaders   []string `json:"allowedHeaders" description:"Request headers allowed in cross-origin requests."`
+	ExposedHeaders   []string `json:"exposedHeaders" description:"Response headers exposed to cross-origin requests."`
+	AllowCredentials *bool    `json:"allowCredentials" description:"Allows cookies and authorization headers (requires explicit origins)."`
+	MaxAge           int      `json:"maxAge" validate:"min=0,max=600" description:"Seconds preflight responses may be cached for."`
+}
+
+// Inherit returns the policy with unset fields taken from parent.
+func (p CorsPolicy) Inherit(parent CorsPolicy) CorsPolicy {
+	if p.Disable == nil {
+		p.Disable = parent.Disable
+	}
+	if len(p.AllowedOrigins) == 0 {
+		p.AllowedOrigins = parent.AllowedOrigins
+	}
+	if len(p.AllowedMethods) == 0 {
+		p.AllowedMethods = parent.AllowedMethods
+	}
+	if len(p.AllowedHeaders) == 0 {
+		p.AllowedHeaders = parent.AllowedHeaders
+	}
+	if len(p.ExposedHeaders) == 0 {
+		p.ExposedHeaders = parent.ExposedHeaders
+	}
+	if p.AllowCredentials == nil {
+		p.AllowCredentials = parent.AllowCredentials
+	}
+	if p.MaxAge == 0 {
+		p.MaxAge = parent.MaxAge
+	}
+	return p
+}
+
+// WithDefaults returns the policy with unset fields set to their defaults.
+func (p CorsPolicy) WithDefaults() CorsPolicy {
+	return p.Inherit(CorsPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"OPTIONS", "DELETE", "GET", "HEAD", "POST", "PUT"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+	})
+}
+
+// IsDisabled returns whether CORS handling is disabled by the policy.
+func (p CorsPolicy) IsDisabled() bool {
+	return p.Disable != nil && *p.Disable
+}
+
+// AllowsCredentials returns whether the policy allows credentials.
+func (p CorsPolicy) AllowsCredentials() bool {
+	return p.AllowCredentials != nil && *p.AllowCredentials
+}
+
+// AllowsAnyOrigin returns whether the policy accepts requests from any origin.
+func (p CorsPolicy) AllowsAnyOrigin() bool {
+	if len(p.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateFields checks the origins of the policy and its router overrides.
+func (c *Cors) ValidateFields() error {
+	errs := validateCorsPolicy("", c.CorsPolicy)
+	for prefix, policy := range c.Routers {
+		routerPath := fmt.Sprintf("routers.%s", prefix)
+		if !strings.HasPrefix(prefix, "/") {
+			errs = append(errs, &FieldError{Path: routerPath, Message: "must be a path prefix starting with /"})
+		}
+		appendFieldsErrors(&errs, routerPath, ValidateStruct(&policy))
+		errs = append(errs, validateCorsPolicy(routerPath, policy.Inherit(c.CorsPolicy))...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateCorsPolicy(queryPath string, policy CorsPolicy) ValidationErrors {
+	var errs ValidationErrors
+	for i, origin := range policy.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+		if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 || len(strings.Trim(u.Path, "/")) > 0 ||
+			strings.Contains(u.Host, "*") {
+			errs = append(errs, &FieldError{
+				Path:    joinPath(queryPath, fmt.Sprintf("allowedOrigins.%d", i)),
+				Message: fmt.Sprintf("must be an origin like https://example.com or https://*.example.com, got %q", origin),
+			})
+		}
+	}
+	if policy.AllowsCredentials() && policy.AllowsAnyOrigin() && !policy.IsDisabled() {
+		errs = append(errs, &FieldError{
+			Path:    joinPath(queryPath, "allowCredentials"),
+			Message: "requires allowedOrigins without \"*\"",
+		})
+	}
+	return errs
+}
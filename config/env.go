@@ -0,0 +1,126 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// EnvName returns the environment variable name for a json query path (e.g.
+// "http.tlsCRT" with prefix "PLATFORM" is PLATFORM_HTTP_TLS_CRT).
+func EnvName(prefix string, queryPath string) string {
+	var segments []string
+	if len(prefix) > 0 {
+		segments = append(segments, strings.ToUpper(prefix))
+	}
+	for _, segment := range strings.Split(queryPath, ".") {
+		segments = append(segments, upperSnakeCase(segment))
+	}
+	return strings.Join(segments, "_")
+}
+
+// applyEnvOverrides walks the configurer's fields (following json tags into
+// nested structs) and overrides any field that has a matching environment
+// variable.
+func applyEnvOverrides(c Configurer, prefix string) error {
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("configurer must be a pointer to a struct, got %T", c)
+	}
+	return walkFields(v.Elem(), "", func(field reflect.Value, structField reflect.StructField, queryPath string) error {
+		name := EnvName(prefix, queryPath)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil
+		}
+		if !field.CanSet() {
+			return fmt.Errorf("environment variable %s can't override a field of an unexported embedded struct", name)
+		}
+		err := setFromString(field, value)
+		if err != nil {
+			return fmt.Errorf("invalid value for environment variable %s: %s", name, err)
+		}
+		return nil
+	})
+}
+
+// setFromString parses value into the field as a function of the field's
+// kind. Pointers are allocated and set from the value they point to. Slices,
+// maps and arrays are parsed as json.
+func setFromString(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(field.Type().Elem())
+		err := setFromString(elem.Elem(), value)
+		if err != nil {
+			return err
+		}
+		field.Set(elem)
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return json.Unmarshal([]byte(value), field.Addr().Interface())
+	}
+	return nil
+}
+
+// upperSnakeCase converts a camelCase name to UPPER_SNAKE_CASE, keeping
+// acronyms together (e.g. "tlsCRT" is TLS_CRT and "HTTPPort" is HTTP_PORT).
+func upperSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				b.WriteRune('_')
+			}
+		}
+		if r == '-' || r == '.' {
+			r = '_'
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		prefix    string
+		queryPath string
+		want      string
+	}{
+		{"PLATFORM", "http.port", "PLATFORM_HTTP_PORT"},
+		{"platform", "http.port", "PLATFORM_HTTP_PORT"},
+		{"", "http.port", "HTTP_PORT"},
+		{"PLATFORM", "http.tlsCRT", "PLATFORM_HTTP_TLS_CRT"},
+		{"PLATFORM", "http.clientIPHeader", "PLATFORM_HTTP_CLIENT_IP_HEADER"},
+		{"PLATFORM", "http.throttle.redis.addr", "PLATFORM_HTTP_THROTTLE_REDIS_ADDR"},
+		{"", "HTTPPort", "HTTP_PORT"},
+		{"", "logMaxSize", "LOG_MAX_SIZE"},
+		{"", "v2Enabled", "V2_ENABLED"},
+		{"", "rate-limit", "RATE_LIMIT"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			if got := EnvName(test.prefix, test.queryPath); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+type envTestNested struct {
+	Name string `json:"name"`
+}
+
+type envTestConfig struct {
+	Config
+	Port     int               `json:"port"`
+	Enabled  *bool             `json:"enabled"`
+	Timeout  time.Duration     `json:"timeout"`
+	Labels   map[string]string `json:"labels"`
+	Hosts    []string          `json:"hosts"`
+	Nested   *envTestNested    `json:"nested"`
+	Unset    *envTestNested    `json:"unset"`
+	Internal string            `json:"-"`
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	t.Setenv("TEST_ENV", "staging")
+	t.Setenv("TEST_PORT", "8080")
+	t.Setenv("TEST_ENABLED", "0")
+	t.Setenv("TEST_TIMEOUT", "1m30s")
+	t.Setenv("TEST_LABELS", `{"team": "platform"}`)
+	t.Setenv("TEST_HOSTS", `["a", "b"]`)
+	t.Setenv("TEST_NESTED_NAME", "nested")
+	t.Setenv("TEST_INTERNAL", "ignored")
+
+	c := &envTestConfig{}
+	err := applyEnvOverrides(c, "TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enabled := false
+	want := &envTestConfig{
+		Config:  Config{Env: "staging"},
+		Port:    8080,
+		Enabled: &enabled,
+		Timeout: 90 * time.Second,
+		Labels:  map[string]string{"team": "platform"},
+		Hosts:   []string{"a", "b"},
+		Nested:  &envTestNested{Name: "nested"},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func TestApplyEnvOverridesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"TEST_PORT", "eighty", `invalid value for environment variable TEST_PORT: strconv.ParseInt: parsing "eighty": invalid syntax`},
+		{"TEST_TIMEOUT", "90", `invalid value for environment variable TEST_TIMEOUT: time: missing unit in duration "90"`},
+		{"TEST_ENABLED", "maybe", `invalid value for environment variable TEST_ENABLED: strconv.ParseBool: parsing "maybe": invalid syntax`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(test.name, test.value)
+			err := applyEnvOverrides(&envTestConfig{}, "TEST")
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonField is a struct field as encoding/json sees it. Env overrides, secrets,
+// validation, provenance, strict mode and the schema all name fields through
+// jsonFields so their query paths can't drift apart.
+type jsonField struct {
+	reflect.StructField
+	name string // Json name
+
+	// inline is set for anonymous struct fields without a json name, whose
+	// fields are serialized as if they were the parent's.
+	inline bool
+}
+
+// path returns the query path of the field in a struct at queryPath.
+func (f *jsonField) path(queryPath string) string {
+	if f.inline {
+		return queryPath
+	}
+	return joinPath(queryPath, f.name)
+}
+
+// jsonFields returns the fields of a struct type that encoding/json
+// serializes, in declaration order.
+func jsonFields(t reflect.Type) []*jsonField {
+	var fields []*jsonField
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		tag := structField.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		_, isStruct := nestedStruct(structField.Type)
+		if !structField.IsExported() && !(structField.Anonymous && isStruct) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		inline := structField.Anonymous && len(name) == 0 && isStruct
+		if len(name) == 0 {
+			name = structField.Name
+		}
+		fields = append(fields, &jsonField{StructField: structField, name: name, inline: inline})
+	}
+	return fields
+}
+
+// nestedStruct returns the struct type (following a pointer) that a field of
+// type t holds and whether it's one the walkers descend into. time.Time is
+// treated as a leaf.
+func nestedStruct(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct && t != timeType
+}
+
+// walkFields invokes visit for every leaf field of a struct with the field's
+// json query path. Nested structs are descended into, nil pointers to structs
+// are only allocated when something inside them gets set and interfaces are
+// skipped.
+func walkFields(v reflect.Value, queryPath string,
+	visit func(field reflect.Value, structField reflect.StructField, queryPath string) error) error {
+	for _, f := range jsonFields(v.Type()) {
+		field := v.FieldByIndex(f.Index)
+		fieldPath := f.path(queryPath)
+
+		if _, ok := nestedStruct(f.Type); ok {
+			if field.Kind() != reflect.Pointer {
+				err := walkFields(field, fieldPath, visit)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if !field.IsNil() {
+				err := walkFields(field.Elem(), fieldPath, visit)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Walk a scratch value so nil pointers are only allocated when
+			// something inside actually gets set.
+			scratch := reflect.New(field.Type().Elem())
+			err := walkFields(scratch.Elem(), fieldPath, visit)
+			if err != nil {
+				return err
+			}
+			if !scratch.Elem().IsZero() && field.CanSet() {
+				field.Set(scratch)
+			}
+			continue
+		}
+		if field.Kind() == reflect.Interface {
+			continue
+		}
+		err := visit(field, f.StructField, fieldPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinPath(queryPath string, name string) string {
+	if len(queryPath) == 0 {
+		return name
+	}
+	return queryPath + "." + name
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
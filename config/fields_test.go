@@ -0,0 +1,81 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fieldsTestEmbedded struct {
+	Region string `json:"region" validate:"required"`
+}
+
+type fieldsTestPointerEmbedded struct {
+	Zone string `json:"zone" validate:"required"`
+}
+
+type fieldsTestNested struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type fieldsTestConfig struct {
+	fieldsTestEmbedded
+	*fieldsTestPointerEmbedded `json:",omitempty"`
+	Nested                     fieldsTestNested  `json:"nested"`
+	Optional                   *fieldsTestNested `json:"optional,omitempty"`
+	Named                      fieldsTestNested  `json:"named" validate:"required"`
+	Started                    time.Time         `json:"started" validate:"required"`
+	Skipped                    string            `json:"-"`
+	internal                   string
+}
+
+func TestJsonFieldPathsAgree(t *testing.T) {
+	// Every walker has to agree on the leaf paths (time.Time is a leaf).
+	wantLeaves := []string{"named.name", "nested.name", "optional.name", "region", "started", "zone"}
+
+	var leaves []string
+	walkFields(reflect.ValueOf(&fieldsTestConfig{}).Elem(), "",
+		func(field reflect.Value, structField reflect.StructField, queryPath string) error {
+			leaves = append(leaves, queryPath)
+			return nil
+		})
+	sort.Strings(leaves)
+	if !reflect.DeepEqual(leaves, wantLeaves) {
+		t.Errorf("got walked paths %v, want %v", leaves, wantLeaves)
+	}
+
+	c := &fieldsTestConfig{fieldsTestPointerEmbedded: &fieldsTestPointerEmbedded{}, Optional: &fieldsTestNested{}}
+	var validated []string
+	for _, fieldErr := range ValidateStruct(c).(ValidationErrors) {
+		validated = append(validated, fieldErr.Path)
+	}
+	sort.Strings(validated)
+	if want := []string{"named", "named.name", "nested.name", "optional.name", "region", "started", "zone"}; !reflect.DeepEqual(validated, want) {
+		t.Errorf("got validated paths %v, want %v", validated, want)
+	}
+
+	var properties []string
+	for name := range typeSchema(reflect.TypeOf(fieldsTestConfig{}), map[reflect.Type]bool{})["properties"].(map[string]interface{}) {
+		properties = append(properties, name)
+	}
+	sort.Strings(properties)
+	if want := []string{"named", "nested", "optional", "region", "started", "zone"}; !reflect.DeepEqual(properties, want) {
+		t.Errorf("got schema properties %v, want %v", properties, want)
+	}
+
+	for _, leaf := range wantLeaves {
+		var types []reflect.Type
+		known := true
+		for _, key := range strings.Split(leaf, ".") {
+			types, known = childTypes(append(types, reflect.TypeOf(fieldsTestConfig{})), key)
+			if !known {
+				break
+			}
+		}
+		if !known {
+			t.Errorf("strict mode doesn't know %s", leaf)
+		}
+	}
+}
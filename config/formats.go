@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configExtensions are the supported configuration file formats. Every format
+// is converted to json, so fields are always mapped by their json tags.
+var configExtensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+// position is a 1-based line and column within a configuration file.
+type position struct {
+	line   int
+	column int
+}
+
+// IsConfigFilename returns whether the filename looks like a configuration
+// file (e.g. config-production.yaml).
+func IsConfigFilename(filename string) bool {
+	base := filepath.Base(filename)
+	for _, extension := range configExtensions {
+		if strings.HasPrefix(base, "config-") && strings.HasSuffix(base, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// findLayerFile returns the configuration file for the environment in any of
+// the supported formats from the first search directory that has one. It's an
+// error for more than one format to exist in that directory.
+func findLayerFile(name string, searchDirs []string) (string, error) {
+	if len(searchDirs) == 0 {
+		searchDirs = []string{"."}
+	}
+
+	for _, dir := range searchDirs {
+		var found []string
+		for _, extension := range configExtensions {
+			filename := filepath.Join(dir, fmt.Sprintf("config-%s%s", name, extension))
+			if _, err := os.Stat(filename); err == nil {
+				found = append(found, filename)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		}
+		return "", fmt.Errorf("multiple configuration files for %s, keep only one of: %s", name,
+			strings.Join(found, ", "))
+	}
+	return "", fmt.Errorf("no configuration file for %s (looked for config-%s{%s} in %s): %w", name, name,
+		strings.Join(configExtensions, ","), strings.Join(searchDirs, ", "), fs.ErrNotExist)
+}
+
+// DefaultSearchDirs returns the directories configuration files are searched
+// for in by default: the executable's directory, /etc/<serviceName> and then
+// the working directory.
+func DefaultSearchDirs(serviceName string) []string {
+	var dirs []string
+	if executable, err := os.Executable(); err == nil {
+		dirs = append(dirs, filepath.Dir(executable))
+	}
+	return append(dirs, filepath.Join("/etc", serviceName), ".")
+}
+
+// convertToJson converts yaml and toml files to json. For yaml, the positions
+// of keys (by json query path) are returned so errors can point at them.
+func convertToJson(filename string, data []byte) ([]byte, map[string]position, error) {
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml":
+		root := &yaml.Node{}
+		err := yaml.Unmarshal(data, root)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to decode %s: %w", filename, err)
+		}
+		var value interface{}
+		err = root.Decode(&value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to decode %s: %w", filename, err)
+		}
+		jsonData, err := json.Marshal(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to convert %s to json: %w", filename, err)
+		}
+		positions := map[string]position{}
+		collectYamlPositions(root, "", positions)
+		return jsonData, positions, nil
+	case ".toml":
+		value := map[string]interface{}{}
+		_, err := toml.Decode(string(data), &value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to decode %s: %w", filename, err)
+		}
+		jsonData, err := json.Marshal(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to convert %s to json: %w", filename, err)
+		}
+		return jsonData, nil, nil
+	}
+	return data, nil, nil
+}
+
+// collectYamlPositions records the position of every mapping key by its json
+// query path.
+func collectYamlPositions(node *yaml.Node, queryPath string, positions map[string]position) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			collectYamlPositions(child, queryPath, positions)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := joinPath(queryPath, key.Value)
+			positions[keyPath] = position{line: key.Line, column: key.Column}
+			collectYamlPositions(value, keyPath, positions)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			collectYamlPositions(child, joinPath(queryPath, fmt.Sprintf("%d", i)), positions)
+		}
+	}
+}
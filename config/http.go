@@ -1,9 +1,134 @@
 package config
 
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
 type Http struct {
-	Port int `json:"port"`
+	Port int `json:"port" validate:"port" description:"Port the server listens on."`
+
+	// AdminEnable opts in to the admin server (health, readiness, version and
+	// runner status) listening on AdminPort. It's started along with the main
+	// server, which needs ServerOptions.Service set for it.
+	AdminEnable bool `json:"adminEnable" description:"Enables the admin server."`
+	AdminPort   int  `json:"adminPort" validate:"port" description:"Port the admin server listens on."`
+
+	// MetricsEnable exposes Prometheus metrics at MetricsPath (defaults to
+	// "/metrics"). Metrics are served by the admin server when it's enabled,
+	// otherwise by the main server.
+	MetricsEnable bool   `json:"metricsEnable" description:"Exposes Prometheus metrics."`
+	MetricsPath   string `json:"metricsPath" description:"Path metrics are served at (defaults to /metrics)."`
+
+	// Throttle rate limits requests (30 per minute per remote address unless
+	// configured otherwise).
+	Throttle Throttle `json:"throttle" description:"Request rate limiting."`
+
+	// Cors is the cross-origin resource sharing policy (any origin unless
+	// configured otherwise).
+	Cors Cors `json:"cors" description:"Cross-origin resource sharing policy."`
+
+	// TrustedProxies lists the addresses (IPs or CIDRs) of proxies and load
+	// balancers whose ClientIPHeader is believed when resolving the client IP.
+	// The header is ignored when empty.
+	TrustedProxies []string `json:"trustedProxies" description:"IPs or CIDRs of proxies trusted to report the client IP."`
+
+	// ClientIPHeader is the header the trusted proxies set: X-Forwarded-For
+	// (the default), Forwarded or X-Real-IP. Only this header is read, the
+	// others are passed through by proxies untouched so clients control them.
+	ClientIPHeader string `json:"clientIPHeader" description:"Header trusted proxies report the client IP in: X-Forwarded-For (default), Forwarded or X-Real-IP."`
+
+	// AccessLogEnable logs every request (with the resolved client IP).
+	AccessLogEnable bool `json:"accessLogEnable" description:"Logs every request."`
+
+	// TLSCRT and TLSKey must be existing files when TLSEnable is set (they're
+	// not checked otherwise, so environments extending production can keep
+	// its paths with TLS off).
+	TLSEnable bool   `json:"tlsEnable" description:"Serves over TLS."`
+	TLSCRT    string `json:"tlsCRT" description:"TLS certificate file."`
+	TLSKey    string `json:"tlsKey" description:"TLS private key file."`
+}
+
+// MetricsRoute returns the path metrics are served at.
+func (h *Http) MetricsRoute() string {
+	if len(h.MetricsPath) > 0 {
+		return h.MetricsPath
+	}
+	return "/metrics"
+}
+
+// clientIPHeaders are the supported values of ClientIPHeader.
+var clientIPHeaders = []string{"X-Forwarded-For", "Forwarded", "X-Real-IP"}
+
+// ClientIPHeaderName returns the header trusted proxies report the client IP
+// in, as spelled in clientIPHeaders.
+func (h *Http) ClientIPHeaderName() string {
+	for _, header := range clientIPHeaders {
+		if strings.EqualFold(header, strings.TrimSpace(h.ClientIPHeader)) {
+			return header
+		}
+	}
+	return "X-Forwarded-For"
+}
+
+// TrustedProxyPrefixes parses TrustedProxies, single IPs are returned as
+// prefixes covering just that address.
+func (h *Http) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(h.TrustedProxies))
+	for _, proxy := range h.TrustedProxies {
+		proxy = strings.TrimSpace(proxy)
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %s", proxy, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %s", proxy, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ValidateFields checks the rules that depend on other fields.
+func (h *Http) ValidateFields() error {
+	var errs ValidationErrors
+	if h.AdminEnable && h.AdminPort == 0 {
+		errs = append(errs, &FieldError{Path: "adminPort", Message: "is required when adminEnable is set"})
+	}
+	if h.AdminEnable && h.AdminPort != 0 && h.AdminPort == h.Port {
+		errs = append(errs, &FieldError{Path: "adminPort", Message: "must differ from port"})
+	}
+	if h.TLSEnable {
+		errs = append(errs, validateTLSFile("tlsCRT", h.TLSCRT)...)
+		errs = append(errs, validateTLSFile("tlsKey", h.TLSKey)...)
+	}
+	if len(h.ClientIPHeader) > 0 && !strings.EqualFold(h.ClientIPHeaderName(), strings.TrimSpace(h.ClientIPHeader)) {
+		errs = append(errs, &FieldError{
+			Path:    "clientIPHeader",
+			Message: fmt.Sprintf("must be one of %s", strings.Join(clientIPHeaders, ", ")),
+		})
+	}
+	if _, err := h.TrustedProxyPrefixes(); err != nil {
+		errs = append(errs, &FieldError{Path: "trustedProxies", Message: err.Error()})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
 
-	TLSEnable bool   `json:"tlsEnable"`
-	TLSCRT    string `json:"tlsCRT"`
-	TLSKey    string `json:"tlsKey"`
+func validateTLSFile(queryPath string, path string) ValidationErrors {
+	if len(path) == 0 {
+		return ValidationErrors{{Path: queryPath, Message: "is required when tlsEnable is set"}}
+	}
+	if message := checkFile(path); len(message) > 0 {
+		return ValidationErrors{{Path: queryPath, Message: message}}
+	}
+	return nil
 }
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// localLayer is the optional, git-ignored layer applied on top of everything
+// else (e.g. config-local.json). It's never applied in production, so a stray
+// local file on a production host can't override production values.
+const localLayer = "local"
+
+// layer is a single configuration file in the layering chain.
+type layer struct {
+	name     string // Environment name (e.g. "production")
+	filename string
+	data     []byte // Json (converted from the file's format if needed)
+
+	// positions of keys by query path for files that were converted to json
+	// (nil when positions can't be determined, e.g. toml).
+	positions map[string]position
+}
+
+// layerHeader holds the keys we read from every layer before decoding it.
+type layerHeader struct {
+	// Extends names the environment this layer is layered on top of.
+	Extends string `json:"extends"`
+}
+
+// resolveLayers returns the configuration layers for the environment in the
+// order they should be decoded (the root first).
+//
+// Unless an explicit list of layers is given in the options, the chain is
+// built by following each file's "extends" key. For backwards compatibility,
+// development extends production when it doesn't say otherwise. If a
+// config-local file exists and the environment isn't production, it's layered
+// on top of the environment.
+func resolveLayers(env string, options *LoadOptions) ([]*layer, error) {
+	if len(options.Layers) > 0 {
+		var layers []*layer
+		for _, name := range options.Layers {
+			l, err := readLayer(name, "", options.SearchDirs)
+			if err != nil {
+				return nil, err
+			}
+			layers = append(layers, l)
+		}
+		return layers, nil
+	}
+
+	name, child := env, ""
+	if env != "production" {
+		if _, err := findLayerFile(localLayer, options.SearchDirs); !errors.Is(err, fs.ErrNotExist) {
+			name = localLayer
+		}
+	}
+
+	var chain []*layer
+	visited := map[string]bool{}
+	for len(name) > 0 {
+		if visited[name] {
+			var names []string
+			for i := len(chain) - 1; i >= 0; i-- {
+				names = append(names, chain[i].name)
+			}
+			return nil, fmt.Errorf("config layering cycle detected: %s -> %s",
+				strings.Join(names, " -> "), name)
+		}
+		visited[name] = true
+
+		l, err := readLayer(name, child, options.SearchDirs)
+		if err != nil {
+			return nil, err
+		}
+		chain = append([]*layer{l}, chain...)
+
+		header := &layerHeader{}
+		err = json.Unmarshal(l.data, header)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode %s: %w", l.filename, err)
+		}
+
+		child = l.filename
+		name = header.Extends
+		if len(name) == 0 {
+			name = defaultParent(l.name, env, visited)
+		}
+	}
+	return chain, nil
+}
+
+// defaultParent returns the environment a layer extends when it doesn't have
+// an "extends" key.
+func defaultParent(name string, env string, visited map[string]bool) string {
+	switch {
+	case name == localLayer && env != localLayer:
+		return env
+	case name == "development" && !visited["production"]:
+		return "production"
+	}
+	return ""
+}
+
+// readLayer reads the layer's file. The child is the file that extends this
+// layer (used in errors).
+func readLayer(name string, child string, searchDirs []string) (*layer, error) {
+	filename, err := findLayerFile(name, searchDirs)
+	if err != nil {
+		if len(child) > 0 && errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w (extended by %s)", err, child)
+		}
+		return nil, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", filename, err)
+	}
+	data, positions, err := convertToJson(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	return &layer{name: name, filename: filename, data: data, positions: positions}, nil
+}
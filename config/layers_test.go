@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveLayers(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		env     string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "development extends production by default",
+			files: map[string]string{"production": `{}`, "development": `{}`},
+			env:   "development",
+			want:  []string{"production", "development"},
+		},
+		{
+			name:  "production has no parent",
+			files: map[string]string{"production": `{}`, "development": `{}`},
+			env:   "production",
+			want:  []string{"production"},
+		},
+		{
+			name: "extends",
+			files: map[string]string{
+				"production": `{}`,
+				"staging":    `{"extends": "production"}`,
+				"qa":         `{"extends": "staging"}`,
+			},
+			env:  "qa",
+			want: []string{"production", "staging", "qa"},
+		},
+		{
+			name:  "local layered over development",
+			files: map[string]string{"production": `{}`, "development": `{}`, "local": `{}`},
+			env:   "development",
+			want:  []string{"production", "development", "local"},
+		},
+		{
+			name:  "local ignored in production",
+			files: map[string]string{"production": `{}`, "local": `{}`},
+			env:   "production",
+			want:  []string{"production"},
+		},
+		{
+			name:    "missing parent",
+			files:   map[string]string{"staging": `{"extends": "base"}`},
+			env:     "staging",
+			wantErr: "(extended by ",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"staging": `{"extends": "qa"}`,
+				"qa":      `{"extends": "staging"}`,
+			},
+			env:     "staging",
+			wantErr: "config layering cycle detected: staging -> qa -> staging",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				err := os.WriteFile(filepath.Join(dir, "config-"+name+".json"), []byte(content), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			layers, err := resolveLayers(test.env, &LoadOptions{SearchDirs: []string{dir}})
+			if len(test.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("got error %v, want it to contain %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, l := range layers {
+				names = append(names, l.name)
+			}
+			if !reflect.DeepEqual(names, test.want) {
+				t.Errorf("got layers %v, want %v", names, test.want)
+			}
+		})
+	}
+}
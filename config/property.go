@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/jsonq"
+)
+
+// PropertyFormat controls how property values are printed.
+type PropertyFormat string
+
+const (
+	// PropertyFormatRaw prints scalars as plain text and objects/arrays as
+	// json.
+	PropertyFormatRaw PropertyFormat = "raw"
+
+	// PropertyFormatJson prints values as json (multiple properties are
+	// printed as a single object keyed by query path).
+	PropertyFormatJson PropertyFormat = "json"
+
+	// PropertyFormatShellExport prints "export NAME='value'" lines, naming
+	// variables after the query path (e.g. "http.port" is HTTP_PORT).
+	PropertyFormatShellExport PropertyFormat = "shell-export"
+)
+
+// ParsePropertyFormat validates a property format name.
+func ParsePropertyFormat(name string) (PropertyFormat, error) {
+	switch format := PropertyFormat(name); format {
+	case PropertyFormatRaw, PropertyFormatJson, PropertyFormatShellExport:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown property format %q (expected raw, json or shell-export)", name)
+}
+
+// ReadConfigValue reads a value of any json type (including whole objects and
+// arrays) from the config using a query path (e.g. "objectA.arrayB.0"). Secret
+// fields are redacted.
+func ReadConfigValue(c Configurer, queryPath string) (interface{}, error) {
+	data, err := RedactedMap(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(queryPath) == 0 {
+		return data, nil
+	}
+	value, err := jsonq.NewQuery(data).Interface(strings.Split(queryPath, ".")...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find value for %s: %s", queryPath, err)
+	}
+	return value, nil
+}
+
+// FormatRawValue formats a value read with ReadConfigValue as plain text.
+// Numbers use the shortest representation (e.g. 8080 and 0.25) and objects or
+// arrays are formatted as json.
+func FormatRawValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// FormatProperties formats values (keyed by query path) in the given format.
+// The query paths are output in the given order.
+func FormatProperties(queryPaths []string, values map[string]interface{}, format PropertyFormat) (string, error) {
+	switch format {
+	case PropertyFormatJson:
+		var b []byte
+		var err error
+		if len(queryPaths) == 1 {
+			b, err = json.Marshal(values[queryPaths[0]])
+		} else {
+			b, err = json.Marshal(values)
+		}
+		return string(b), err
+	case PropertyFormatShellExport:
+		lines := make([]string, len(queryPaths))
+		for i, queryPath := range queryPaths {
+			raw, err := FormatRawValue(values[queryPath])
+			if err != nil {
+				return "", err
+			}
+			lines[i] = fmt.Sprintf("export %s=%s", EnvName("", queryPath), shellQuote(raw))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	lines := make([]string, len(queryPaths))
+	for i, queryPath := range queryPaths {
+		raw, err := FormatRawValue(values[queryPath])
+		if err != nil {
+			return "", err
+		}
+		lines[i] = raw
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// ReadConfigProperties reads each query path from the first configurer that
+// has it and returns the values keyed by query path. Query paths that don't
+// match a config field are dispatched to the configurer's ReadProperty, so
+// configurers can serve computed or derived properties.
+func ReadConfigProperties(queryPaths []string, configurers ...Configurer) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	var missing []string
+	for _, queryPath := range queryPaths {
+		found := false
+		for _, c := range configurers {
+			if c == nil {
+				continue
+			}
+			value, err := ReadConfigValue(c, queryPath)
+			if err != nil {
+				value, err = readPropertyValue(c, queryPath)
+			}
+			if err == nil {
+				values[queryPath] = value
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, queryPath)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return values, fmt.Errorf("unable to find value for %s", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+// readPropertyValue reads a query path through the configurer's ReadProperty.
+// Objects and arrays (which ReadProperty returns as json) are decoded so
+// they're formatted like values read with ReadConfigValue.
+func readPropertyValue(c Configurer, queryPath string) (interface{}, error) {
+	raw, err := c.ReadProperty(queryPath)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := strings.TrimSpace(raw)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var value interface{}
+		if json.Unmarshal([]byte(trimmed), &value) == nil {
+			return value, nil
+		}
+	}
+	return raw, nil
+}
+
+// shellQuote single quotes a value for POSIX shells.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
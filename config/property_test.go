@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// computedConfig serves a computed property through ReadProperty in addition
+// to its fields.
+type computedConfig struct {
+	Config
+	Host string `json:"host"`
+}
+
+func (c *computedConfig) ReadProperty(queryPath string) (string, error) {
+	switch queryPath {
+	case "url":
+		return "https://" + c.Host, nil
+	case "hosts":
+		return fmt.Sprintf(`["%s"]`, c.Host), nil
+	}
+	return ReadConfigProperty(c, queryPath)
+}
+
+func TestReadConfigProperties(t *testing.T) {
+	platform := &Config{Env: "production"}
+	computed := &computedConfig{Host: "example.com"}
+
+	tests := []struct {
+		name       string
+		queryPaths []string
+		want       map[string]interface{}
+		wantErr    bool
+	}{
+		{"field", []string{"host"}, map[string]interface{}{"host": "example.com"}, false},
+		{"platform first", []string{"env"}, map[string]interface{}{"env": "production"}, false},
+		{"computed", []string{"url"}, map[string]interface{}{"url": "https://example.com"}, false},
+		{"computed json", []string{"hosts"}, map[string]interface{}{"hosts": []interface{}{"example.com"}}, false},
+		{"missing", []string{"host", "nope"}, map[string]interface{}{"host": "example.com"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ReadConfigProperties(test.queryPaths, platform, computed)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MergedRedactedMap merges the configurers (later ones taking precedence) into
+// a single json style map with secret fields redacted. This is the effective
+// configuration across configurers sharing the same files.
+func MergedRedactedMap(configurers ...Configurer) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for _, c := range configurers {
+		if c == nil {
+			continue
+		}
+		data, err := RedactedMap(c)
+		if err != nil {
+			return nil, err
+		}
+		mergeMaps(merged, data)
+	}
+	return merged, nil
+}
+
+// Provenance returns, for every key of the configurers, where its value came
+// from: the file of the last layer that set it, "env:<NAME>" for environment
+// variable overrides or "default" when nothing set it.
+func Provenance(options *LoadOptions, configurers ...Configurer) (map[string]string, error) {
+	env := os.Getenv("GO_ENV")
+	if len(env) < 1 {
+		env = "development"
+	}
+
+	provenance := map[string]string{}
+	merged, err := MergedRedactedMap(configurers...)
+	if err != nil {
+		return nil, err
+	}
+	for _, queryPath := range leafPaths("", merged) {
+		provenance[queryPath] = "default"
+	}
+
+	layers, err := resolveLayers(env, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range layers {
+		data := map[string]interface{}{}
+		err = json.Unmarshal(l.data, &data)
+		if err != nil {
+			return nil, l.describeDecodeError(err)
+		}
+		delete(data, "extends")
+		for _, queryPath := range leafPaths("", data) {
+			provenance[queryPath] = l.filename
+		}
+	}
+
+	for _, c := range configurers {
+		v := reflect.ValueOf(c)
+		if c == nil || v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		walkFields(v.Elem(), "", func(field reflect.Value, structField reflect.StructField, queryPath string) error {
+			name := EnvName(options.EnvPrefix, queryPath)
+			if _, ok := os.LookupEnv(name); ok {
+				provenance[queryPath] = fmt.Sprintf("env:%s", name)
+			}
+			return nil
+		})
+	}
+	return provenance, nil
+}
+
+// FormatProvenance formats provenance as aligned "key source" lines sorted by
+// key.
+func FormatProvenance(provenance map[string]string) string {
+	keys := make([]string, 0, len(provenance))
+	width := 0
+	for key := range provenance {
+		keys = append(keys, key)
+		width = max(width, len(key))
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = fmt.Sprintf("%-*s  %s", width, key, provenance[key])
+	}
+	return strings.Join(lines, "\n")
+}
+
+// leafPaths returns the query paths of every non-object value (arrays are
+// treated as values since they're replaced wholesale when layered).
+func leafPaths(queryPath string, data map[string]interface{}) []string {
+	var paths []string
+	for key, value := range data {
+		keyPath := joinPath(queryPath, key)
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			paths = append(paths, leafPaths(keyPath, child)...)
+			continue
+		}
+		paths = append(paths, keyPath)
+	}
+	return paths
+}
+
+// mergeMaps deep merges src into dst.
+func mergeMaps(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		srcChild, srcIsMap := value.(map[string]interface{})
+		dstChild, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeMaps(dstChild, srcChild)
+			continue
+		}
+		dst[key] = value
+	}
+}
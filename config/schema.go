@@ -0,0 +1,175 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonSchemaDialect is the JSON Schema version we generate.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema generates a JSON Schema for configuration files shared by the
+// configurers. Field names follow json tags, descriptions come from the
+// `description` struct tag and `validate` rules become required fields, enums
+// and bounds.
+func Schema(configurers ...Configurer) map[string]interface{} {
+	root := map[string]interface{}{
+		"$schema": jsonSchemaDialect,
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"extends": map[string]interface{}{
+				"type":        "string",
+				"description": "Environment this configuration file is layered on top of.",
+			},
+		},
+		"additionalProperties": false,
+	}
+	for _, c := range configurers {
+		if c == nil {
+			continue
+		}
+		t := indirectType(reflect.TypeOf(c))
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+		addStructProperties(root, t, map[reflect.Type]bool{})
+	}
+	if required, ok := root["required"].([]string); ok {
+		sort.Strings(required)
+	}
+	return root
+}
+
+// typeSchema returns the schema of a type. Visiting tracks the structs being
+// expanded so recursive types don't recurse forever.
+func typeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	t = indirectType(t)
+	switch {
+	case t == durationType:
+		return map[string]interface{}{"type": "integer", "description": "Duration in nanoseconds."}
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           map[string]interface{}{},
+			"additionalProperties": false,
+		}
+		addStructProperties(schema, t, visiting)
+		return schema
+	}
+	return map[string]interface{}{}
+}
+
+// addStructProperties adds the struct's fields to an object schema.
+func addStructProperties(schema map[string]interface{}, t reflect.Type, visiting map[reflect.Type]bool) {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	properties := schema["properties"].(map[string]interface{})
+	for _, f := range jsonFields(t) {
+		structField, name := f.StructField, f.name
+		fieldType := indirectType(structField.Type)
+		if f.inline {
+			addStructProperties(schema, fieldType, visiting)
+			continue
+		}
+		if fieldType.Kind() == reflect.Interface {
+			continue
+		}
+
+		fieldSchema := typeSchema(fieldType, visiting)
+		if description := structField.Tag.Get("description"); len(description) > 0 {
+			fieldSchema["description"] = description
+		}
+		if structField.Tag.Get("secret") == "true" {
+			fieldSchema["writeOnly"] = true
+		}
+		for _, rule := range splitRules(structField.Tag.Get("validate")) {
+			if applyRuleToSchema(fieldSchema, fieldType, rule) {
+				required, _ := schema["required"].([]string)
+				if !containsString(required, name) {
+					schema["required"] = append(required, name)
+				}
+			}
+		}
+		properties[name] = fieldSchema
+	}
+}
+
+// applyRuleToSchema adds a validation rule to a field's schema and returns
+// whether the rule makes the field required.
+func applyRuleToSchema(schema map[string]interface{}, t reflect.Type, rule string) bool {
+	name, arg, _ := strings.Cut(rule, "=")
+	isNumber := schema["type"] == "integer" || schema["type"] == "number"
+	switch name {
+	case "required":
+		return true
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return false
+		}
+		keyword := map[string]string{"min": "minimum", "max": "maximum"}[name]
+		if !isNumber {
+			keyword = map[string]string{"min": "minLength", "max": "maxLength"}[name]
+			if schema["type"] == "array" {
+				keyword = map[string]string{"min": "minItems", "max": "maxItems"}[name]
+			}
+		}
+		schema[keyword] = limit
+	case "oneof":
+		var enum []interface{}
+		for _, option := range strings.Fields(arg) {
+			if number, err := strconv.ParseFloat(option, 64); isNumber && err == nil {
+				enum = append(enum, number)
+			} else {
+				enum = append(enum, option)
+			}
+		}
+		if !isNumber && t.Kind() == reflect.String {
+			// Zero values skip validation, so the empty string is allowed too.
+			enum = append(enum, "")
+		}
+		schema["enum"] = enum
+	case "url":
+		schema["format"] = "uri"
+	case "port":
+		schema["minimum"] = 0
+		schema["maximum"] = 65535
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
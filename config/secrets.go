@@ -0,0 +1,267 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Redacted replaces the value of secret fields in summaries and property reads.
+const Redacted = "[redacted]"
+
+// secretReferencePattern matches references like "${file:/run/secrets/token}".
+var secretReferencePattern = regexp.MustCompile(`\$\{([a-zA-Z][a-zA-Z0-9_-]*):([^}]*)\}`)
+
+// SecretResolver resolves the reference part of a "${<scheme>:<reference>}"
+// secret reference in a configuration string into its value.
+type SecretResolver interface {
+	Resolve(reference string) (string, error)
+}
+
+// SecretResolverFunc adapts a function into a SecretResolver.
+type SecretResolverFunc func(reference string) (string, error)
+
+func (f SecretResolverFunc) Resolve(reference string) (string, error) {
+	return f(reference)
+}
+
+// defaultSecretResolvers are always available unless overridden in the load
+// options.
+var defaultSecretResolvers = map[string]SecretResolver{
+	// ${env:NAME} reads the environment variable NAME.
+	"env": SecretResolverFunc(func(reference string) (string, error) {
+		value, ok := os.LookupEnv(reference)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", reference)
+		}
+		return value, nil
+	}),
+
+	// ${file:/path} reads the file at path (dropping any trailing newline).
+	"file": SecretResolverFunc(func(reference string) (string, error) {
+		b, err := os.ReadFile(reference)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}),
+}
+
+// resolveSecrets replaces secret references in every string field of the
+// configurer (including strings inside slices, maps and their struct elements)
+// with their resolved values.
+func resolveSecrets(c Configurer, resolvers map[string]SecretResolver) error {
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("configurer must be a pointer to a struct, got %T", c)
+	}
+	return walkFields(v.Elem(), "", func(field reflect.Value, structField reflect.StructField, queryPath string) error {
+		return resolveSecretValue(field, queryPath, resolvers)
+	})
+}
+
+// resolveSecretValue resolves the secret references in a settable value,
+// descending into pointers, slices, arrays, maps and structs.
+func resolveSecretValue(v reflect.Value, queryPath string, resolvers map[string]SecretResolver) error {
+	switch v.Kind() {
+	case reflect.String:
+		if !strings.Contains(v.String(), "${") {
+			return nil
+		}
+		if !v.CanSet() {
+			return fmt.Errorf("unable to resolve %s: fields of unexported embedded structs can't be set", queryPath)
+		}
+		value, err := resolveSecretString(v.String(), queryPath, resolvers)
+		if err != nil {
+			return err
+		}
+		v.SetString(value)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return resolveSecretValue(v.Elem(), queryPath, resolvers)
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return nil
+		}
+		return walkFields(v, queryPath, func(field reflect.Value, structField reflect.StructField, fieldPath string) error {
+			return resolveSecretValue(field, fieldPath, resolvers)
+		})
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			err := resolveSecretValue(v.Index(i), joinPath(queryPath, strconv.Itoa(i)), resolvers)
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values aren't settable, so resolve a copy and store it back.
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			err := resolveSecretValue(value, joinPath(queryPath, fmt.Sprint(iter.Key().Interface())), resolvers)
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+	}
+	return nil
+}
+
+// resolveSecretString replaces each secret reference in value with its
+// resolved value.
+func resolveSecretString(value string, queryPath string, resolvers map[string]SecretResolver) (string, error) {
+	var resolveErr error
+	resolved := secretReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		match := secretReferencePattern.FindStringSubmatch(reference)
+		resolver, ok := resolvers[match[1]]
+		if !ok {
+			resolver, ok = defaultSecretResolvers[match[1]]
+		}
+		if !ok {
+			resolveErr = fmt.Errorf("unable to resolve %s: no secret resolver for scheme %q", queryPath, match[1])
+			return reference
+		}
+		resolved, err := resolver.Resolve(match[2])
+		if err != nil {
+			resolveErr = fmt.Errorf("unable to resolve %s: %s", queryPath, err)
+			return reference
+		}
+		return resolved
+	})
+	return resolved, resolveErr
+}
+
+// secretPaths returns the json query paths of fields tagged `secret:"true"`.
+// Fields inside the elements of slices, arrays and maps are given a "*"
+// segment in place of the index or key (e.g. "databases.*.password").
+func secretPaths(c interface{}) []string {
+	v := reflect.ValueOf(c)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return secretTypePaths(v.Type(), "")
+}
+
+// secretTypePaths returns the secret field paths of a struct type.
+func secretTypePaths(t reflect.Type, queryPath string) []string {
+	var paths []string
+	walkFields(reflect.New(t).Elem(), queryPath, func(field reflect.Value, structField reflect.StructField, fieldPath string) error {
+		if structField.Tag.Get("secret") == "true" {
+			paths = append(paths, fieldPath)
+			return nil
+		}
+
+		// Look for secret fields in struct elements.
+		elemType := indirectType(field.Type())
+		elemPath := fieldPath
+		for elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Array || elemType.Kind() == reflect.Map {
+			elemType = indirectType(elemType.Elem())
+			elemPath = joinPath(elemPath, "*")
+		}
+		if _, ok := nestedStruct(elemType); ok && elemPath != fieldPath {
+			paths = append(paths, secretTypePaths(elemType, elemPath)...)
+		}
+		return nil
+	})
+	return paths
+}
+
+// RedactedMap converts the configurer into a json style map with the values of
+// secret fields replaced by Redacted.
+func RedactedMap(c Configurer) (map[string]interface{}, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{}
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range secretPaths(c) {
+		redact(data, strings.Split(path, "."))
+	}
+	return data, nil
+}
+
+// redact replaces the value at the path of segments (where "*" matches every
+// element or key) with Redacted. Null values are left as they are.
+func redact(data interface{}, segments []string) {
+	last := len(segments) == 1
+	switch node := data.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if segments[0] != "*" && segments[0] != key {
+				continue
+			}
+			if !last {
+				redact(child, segments[1:])
+			} else if child != nil {
+				node[key] = Redacted
+			}
+		}
+	case []interface{}:
+		if segments[0] != "*" {
+			return
+		}
+		for i, child := range node {
+			if !last {
+				redact(child, segments[1:])
+			} else if child != nil {
+				node[i] = Redacted
+			}
+		}
+	}
+}
+
+// LogRedactedSummary prints every configuration field (with secret fields
+// redacted). Configurers can use it to implement LogSummary.
+func LogRedactedSummary(title string, c Configurer) {
+	data, err := RedactedMap(c)
+	if err != nil {
+		log.Printf("%s: unable to summarize configuration: %s", title, err)
+		return
+	}
+
+	lines := map[string]string{}
+	flattenMap("", data, lines)
+	keys := make([]string, 0, len(lines))
+	for key := range lines {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	log.Printf("%s", title)
+	for _, key := range keys {
+		log.Printf(" %s: %s %s", key, strings.Repeat(".", max(2, 20-len(key))), lines[key])
+	}
+}
+
+func flattenMap(prefix string, data map[string]interface{}, lines map[string]string) {
+	for key, value := range data {
+		if len(prefix) > 0 {
+			key = prefix + "." + key
+		}
+		if child, ok := value.(map[string]interface{}); ok {
+			flattenMap(key, child, lines)
+			continue
+		}
+		lines[key] = fmt.Sprintf("%v", value)
+	}
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type secretsTestDatabase struct {
+	Host     string `json:"host"`
+	Password string `json:"password" secret:"true"`
+}
+
+type secretsTestConfig struct {
+	Config
+	Token     string                          `json:"token" secret:"true"`
+	Hosts     []string                        `json:"hosts"`
+	Headers   map[string]string               `json:"headers"`
+	Databases []secretsTestDatabase           `json:"databases"`
+	Replicas  map[string]*secretsTestDatabase `json:"replicas"`
+}
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("SECRETS_TEST_VALUE", "resolved")
+
+	c := &secretsTestConfig{
+		Token:   "${env:SECRETS_TEST_VALUE}",
+		Hosts:   []string{"a", "${env:SECRETS_TEST_VALUE}"},
+		Headers: map[string]string{"Authorization": "Bearer ${env:SECRETS_TEST_VALUE}"},
+		Databases: []secretsTestDatabase{
+			{Host: "db", Password: "${env:SECRETS_TEST_VALUE}"},
+		},
+		Replicas: map[string]*secretsTestDatabase{
+			"east": {Host: "replica", Password: "${env:SECRETS_TEST_VALUE}"},
+		},
+	}
+	err := resolveSecrets(c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &secretsTestConfig{
+		Token:     "resolved",
+		Hosts:     []string{"a", "resolved"},
+		Headers:   map[string]string{"Authorization": "Bearer resolved"},
+		Databases: []secretsTestDatabase{{Host: "db", Password: "resolved"}},
+		Replicas:  map[string]*secretsTestDatabase{"east": {Host: "replica", Password: "resolved"}},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func TestResolveSecretsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *secretsTestConfig
+		wantErr string
+	}{
+		{"unknown scheme in slice", &secretsTestConfig{Hosts: []string{"${vault:db}"}},
+			`unable to resolve hosts.0: no secret resolver for scheme "vault"`},
+		{"unset variable in map", &secretsTestConfig{Headers: map[string]string{"key": "${env:SECRETS_TEST_UNSET}"}},
+			"unable to resolve headers.key: environment variable SECRETS_TEST_UNSET is not set"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := resolveSecrets(test.config, nil)
+			if err == nil || err.Error() != test.wantErr {
+				t.Errorf("got error %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedactedMap(t *testing.T) {
+	c := &secretsTestConfig{
+		Token:     "token",
+		Databases: []secretsTestDatabase{{Host: "db", Password: "password"}},
+		Replicas:  map[string]*secretsTestDatabase{"east": {Host: "replica", Password: "password"}},
+	}
+	data, err := RedactedMap(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data["token"] != Redacted {
+		t.Errorf("got token %v, want %s", data["token"], Redacted)
+	}
+	database := data["databases"].([]interface{})[0].(map[string]interface{})
+	if database["password"] != Redacted || database["host"] != "db" {
+		t.Errorf("got database %v, want the password redacted", database)
+	}
+	replica := data["replicas"].(map[string]interface{})["east"].(map[string]interface{})
+	if replica["password"] != Redacted || replica["host"] != "replica" {
+		t.Errorf("got replica %v, want the password redacted", replica)
+	}
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// StrictMode controls whether unknown keys in configuration files are errors.
+type StrictMode int
+
+const (
+	// StrictProduction rejects unknown keys only when GO_ENV is production.
+	StrictProduction StrictMode = iota
+
+	// StrictAlways rejects unknown keys in every environment.
+	StrictAlways
+
+	// StrictNever ignores unknown keys.
+	StrictNever
+)
+
+// isStrict returns whether unknown keys should be rejected in the environment.
+func (m StrictMode) isStrict(env string) bool {
+	switch m {
+	case StrictAlways:
+		return true
+	case StrictNever:
+		return false
+	}
+	return env == "production"
+}
+
+// checkUnknownKeys reports every key in the layer that none of the configurers
+// has a field for (following encoding/json's case-insensitive matching). Keys
+// are reported with their file, line and column.
+func checkUnknownKeys(l *layer, configurers []Configurer) error {
+	var types []reflect.Type
+	for _, c := range configurers {
+		if c != nil {
+			types = append(types, reflect.TypeOf(c))
+		}
+	}
+
+	checker := &unknownKeyChecker{
+		layer:   l,
+		decoder: json.NewDecoder(bytes.NewReader(l.data)),
+	}
+	err := checker.checkValue(types, "")
+	if err != nil {
+		return fmt.Errorf("unable to decode %s: %w", l.filename, err)
+	}
+	if len(checker.unknownKeys) > 0 {
+		return fmt.Errorf("unknown configuration keys: %s", strings.Join(checker.unknownKeys, "; "))
+	}
+	return nil
+}
+
+type unknownKeyChecker struct {
+	layer       *layer
+	decoder     *json.Decoder
+	unknownKeys []string
+}
+
+// checkValue reads the next json value, checking object keys against the
+// types the value could be decoded into.
+func (c *unknownKeyChecker) checkValue(types []reflect.Type, queryPath string) error {
+	token, err := c.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		for c.decoder.More() {
+			keyToken, err := c.decoder.Token()
+			if err != nil {
+				return err
+			}
+			key := keyToken.(string)
+			keyPath := joinPath(queryPath, key)
+			keyOffset := keyStart(c.layer.data, c.decoder.InputOffset())
+
+			childTypes, known := childTypes(types, key)
+			if !known && !(len(queryPath) == 0 && key == "extends") {
+				c.unknownKeys = append(c.unknownKeys, fmt.Sprintf("%s: unknown key %q",
+					c.layer.location(keyPath, keyOffset), keyPath))
+			}
+			err = c.checkValue(childTypes, keyPath)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = c.decoder.Token() // Closing brace
+		return err
+	case json.Delim('['):
+		var elemTypes []reflect.Type
+		for _, t := range types {
+			t = indirectType(t)
+			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+				elemTypes = append(elemTypes, t.Elem())
+			} else if t.Kind() == reflect.Interface {
+				elemTypes = append(elemTypes, t)
+			}
+		}
+		for i := 0; c.decoder.More(); i++ {
+			err = c.checkValue(elemTypes, fmt.Sprintf("%s.%d", queryPath, i))
+			if err != nil {
+				return err
+			}
+		}
+		_, err = c.decoder.Token() // Closing bracket
+		return err
+	}
+	return nil
+}
+
+// childTypes returns the types a key's value could be decoded into and whether
+// the key is known. Keys are only unknown when at least one of the parent
+// types is a struct (type mismatches are left for the decoder to report).
+func childTypes(types []reflect.Type, key string) ([]reflect.Type, bool) {
+	var children []reflect.Type
+	known, hasStruct := false, false
+	for _, t := range types {
+		t = indirectType(t)
+		switch t.Kind() {
+		case reflect.Struct:
+			hasStruct = true
+			if child, ok := structFieldType(t, key); ok {
+				children = append(children, child)
+				known = true
+			}
+		case reflect.Map:
+			children = append(children, t.Elem())
+			known = true
+		case reflect.Interface:
+			children = append(children, t)
+			known = true
+		}
+	}
+	return children, known || !hasStruct
+}
+
+// structFieldType finds the type of the field encoding/json would decode the
+// key into (preferring an exact match over a case-insensitive one).
+func structFieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	var foldMatch reflect.Type
+	for _, f := range jsonFields(t) {
+		if f.inline {
+			inlineType, _ := nestedStruct(f.Type)
+			if child, ok := structFieldType(inlineType, key); ok {
+				return child, true
+			}
+			continue
+		}
+		if f.name == key {
+			return f.Type, true
+		}
+		if foldMatch == nil && strings.EqualFold(f.name, key) {
+			foldMatch = f.Type
+		}
+	}
+	return foldMatch, foldMatch != nil
+}
+
+// keyStart returns the offset of the opening quote of the json string that
+// ends (with its closing quote) just before end. Scanning the raw data keeps
+// the offset right for keys written with escape sequences.
+func keyStart(data []byte, end int64) int64 {
+	for i := end - 2; i >= 0; i-- {
+		if data[i] != '"' {
+			continue
+		}
+		backslashes := 0
+		for j := i - 1; j >= 0 && data[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes%2 == 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// location describes where a key is in the layer's file as
+// "<filename>:<line>:<column>". For json files, the position comes from the
+// byte offset; for converted files, it's looked up by query path (falling
+// back to just the filename when unknown).
+func (l *layer) location(queryPath string, offset int64) string {
+	if filepath.Ext(l.filename) == ".json" {
+		if offset > int64(len(l.data)) {
+			offset = int64(len(l.data))
+		}
+		before := l.data[:offset]
+		line := bytes.Count(before, []byte("\n")) + 1
+		column := int(offset) - bytes.LastIndexByte(before, '\n')
+		return fmt.Sprintf("%s:%d:%d", l.filename, line, column)
+	}
+	if p, ok := l.positions[queryPath]; ok {
+		return fmt.Sprintf("%s:%d:%d", l.filename, p.line, p.column)
+	}
+	return l.filename
+}
+
+// describeDecodeError adds the location to json syntax and type errors.
+func (l *layer) describeDecodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("%s: %w", l.location("", syntaxErr.Offset), err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("%s: %w", l.location(typeErr.Field, typeErr.Offset), err)
+	}
+	return fmt.Errorf("unable to decode %s: %w", l.filename, err)
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type strictTestConfig struct {
+	Config
+	Http Http `json:"http"`
+}
+
+type strictTestSharedConfig struct {
+	Config
+	Database struct {
+		Host string `json:"host"`
+	} `json:"database"`
+}
+
+// writeTestLayer writes a production layer with the given extension and
+// content to a temporary directory and reads it back.
+func writeTestLayer(t *testing.T, extension string, content string) *layer {
+	t.Helper()
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "config-production"+extension), []byte(content), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := readLayer("production", "", []string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestCheckUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		extension   string
+		content     string
+		configurers []Configurer
+		want        []string
+	}{
+		{
+			name:        "known keys",
+			extension:   ".json",
+			content:     `{"extends": "", "env": "production", "http": {"port": 8080}}`,
+			configurers: []Configurer{&strictTestConfig{}},
+		},
+		{
+			name:        "case insensitive",
+			extension:   ".json",
+			content:     `{"HTTP": {"Port": 8080}}`,
+			configurers: []Configurer{&strictTestConfig{}},
+		},
+		{
+			name:        "nested unknown key",
+			extension:   ".json",
+			content:     "{\n  \"http\": {\n    \"prot\": 8080\n  }\n}",
+			configurers: []Configurer{&strictTestConfig{}},
+			want:        []string{`config-production.json:3:5: unknown key "http.prot"`},
+		},
+		{
+			name:        "escaped key",
+			extension:   ".json",
+			content:     "{\n  \"http\": {\"port\": 1, \"\\u0078y\\\"z\": 2}\n}",
+			configurers: []Configurer{&strictTestConfig{}},
+			want:        []string{`config-production.json:2:23: unknown key "http.xy\"z"`},
+		},
+		{
+			name:        "shared configurers",
+			extension:   ".json",
+			content:     `{"http": {"port": 8080}, "database": {"host": "db", "hots": "db"}}`,
+			configurers: []Configurer{&strictTestConfig{}, &strictTestSharedConfig{}},
+			want:        []string{`config-production.json:1:53: unknown key "database.hots"`},
+		},
+		{
+			name:        "unknown to every configurer",
+			extension:   ".json",
+			content:     `{"database": {}}`,
+			configurers: []Configurer{&strictTestConfig{}},
+			want:        []string{`config-production.json:1:2: unknown key "database"`},
+		},
+		{
+			name:        "yaml positions",
+			extension:   ".yaml",
+			content:     "env: production\nhttp:\n  port: 8080\n  prot: 8080\nlogFiel: out.log\n",
+			configurers: []Configurer{&strictTestConfig{}},
+			want: []string{
+				`config-production.yaml:4:3: unknown key "http.prot"`,
+				`config-production.yaml:5:1: unknown key "logFiel"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := writeTestLayer(t, test.extension, test.content)
+			err := checkUnknownKeys(l, test.configurers)
+			if len(test.want) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got no error, want %v", test.want)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(err.Error(), filepath.Join(filepath.Dir(l.filename), want)) {
+					t.Errorf("got error %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeLayerTypeError(t *testing.T) {
+	tests := []struct {
+		name      string
+		extension string
+		content   string
+		want      string
+	}{
+		{"json", ".json", "{\n  \"http\": {\n    \"port\": \"eighty\"\n  }\n}", "config-production.json:3:21: "},
+		{"yaml", ".yaml", "http:\n  port: eighty\n", "config-production.yaml:2:3: "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := writeTestLayer(t, test.extension, test.content)
+			err := decodeLayer(l, &strictTestConfig{})
+			want := filepath.Join(filepath.Dir(l.filename), test.want)
+			if err == nil || !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("got error %v, want it to start with %q", err, want)
+			}
+		})
+	}
+}
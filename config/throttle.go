@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Throttle configures request rate limiting for an http server. Requests are
+// limited per key (see VaryBy) and each entry in Routes overrides the limits
+// for a route, keyed by its route template (e.g. "/users/{id}") optionally
+// prefixed with a method (e.g. "POST /login"). Unset route fields inherit from
+// the top-level rule.
+type Throttle struct {
+	ThrottleRule
+	Routes map[string]ThrottleRule `json:"routes" description:"Rate limit overrides keyed by route template, optionally prefixed with a method (e.g. \"POST /login\")."`
+
+	// Store is where request counts are kept. Counts are per instance unless
+	// a shared store is configured.
+	Store ThrottleStore `json:"store" description:"Where request counts are kept."`
+}
+
+// ThrottleStore selects the store for request counts: "memory" (the default)
+// keeps them in process, "redis" shares them between instances through a
+// server speaking the Redis protocol. When the server is unreachable, counts
+// fall back to memory until it's reachable again.
+type ThrottleStore struct {
+	Type string `json:"type" validate:"oneof=memory redis" description:"Store type (defaults to memory)."`
+
+	// Address is a host:port or a redis:// url.
+	Address   string `json:"address" description:"Address of the Redis server (host:port or redis:// url)."`
+	Password  string `json:"password" secret:"true" description:"Password for the Redis server."`
+	DB        int    `json:"db" validate:"min=0" description:"Redis database index."`
+	KeyPrefix string `json:"keyPrefix" description:"Prefix for Redis keys (defaults to \"throttle:\")."`
+}
+
+// ThrottleRule is a rate limit. Zero values use the defaults: 30 requests per
+// minute with a burst of the full limit, keyed by remote address.
+type ThrottleRule struct {
+	// Disable is a pointer so route overrides can turn throttling back on
+	// (false) under a disabled top-level rule, nil inherits.
+	Disable *bool  `json:"disable" description:"Disables rate limiting (false re-enables it for a route)."`
+	Limit   int    `json:"limit" validate:"min=0" description:"Requests allowed per period (defaults to 30)."`
+	Period  string `json:"period" validate:"oneof=second minute hour day" description:"Period the limit applies to (defaults to minute)."`
+	Burst   int    `json:"burst" validate:"min=0" description:"Requests allowed at once (defaults to the limit)."`
+
+	// VaryBy lists what requests are keyed by: "remoteAddr" (the client IP
+	// behind any trusted proxies), "method", "path" and/or "header:<name>"
+	// (e.g. "header:X-API-Key").
+	VaryBy []string `json:"varyBy" description:"What requests are limited by: remoteAddr, method, path or header:<name>."`
+}
+
+// Default throttle rule values.
+const (
+	DefaultThrottleLimit  = 30
+	DefaultThrottlePeriod = "minute"
+)
+
+var throttlePeriods = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+}
+
+// Inherit returns the rule with unset fields taken from parent.
+func (t ThrottleRule) Inherit(parent ThrottleRule) ThrottleRule {
+	if t.Disable == nil {
+		t.Disable = parent.Disable
+	}
+	if t.Limit == 0 {
+		t.Limit = parent.Limit
+	}
+	if len(t.Period) == 0 {
+		t.Period = parent.Period
+	}
+	if t.Burst == 0 {
+		t.Burst = parent.Burst
+	}
+	if len(t.VaryBy) == 0 {
+		t.VaryBy = parent.VaryBy
+	}
+	return t
+}
+
+// WithDefaults returns the rule with unset fields set to their defaults.
+func (t ThrottleRule) WithDefaults() ThrottleRule {
+	t = t.Inherit(ThrottleRule{
+		Limit:  DefaultThrottleLimit,
+		Period: DefaultThrottlePeriod,
+		VaryBy: []string{"remoteAddr"},
+	})
+	if t.Burst == 0 {
+		t.Burst = t.Limit
+	}
+	return t
+}
+
+// IsDisabled returns whether throttling is disabled by the rule.
+func (t ThrottleRule) IsDisabled() bool {
+	return t.Disable != nil && *t.Disable
+}
+
+// PeriodDuration returns the period the limit applies to.
+func (t ThrottleRule) PeriodDuration() time.Duration {
+	if period, ok := throttlePeriods[t.Period]; ok {
+		return period
+	}
+	return time.Minute
+}
+
+// ValidateFields checks the vary-by keys of the rule and its route overrides.
+func (t *Throttle) ValidateFields() error {
+	errs := validateVaryBy("varyBy", t.VaryBy)
+	for route, rule := range t.Routes {
+		routePath := fmt.Sprintf("routes.%s", route)
+		if len(strings.TrimSpace(route)) == 0 {
+			errs = append(errs, &FieldError{Path: routePath, Message: "must name a route"})
+		}
+		appendFieldsErrors(&errs, routePath, ValidateStruct(&rule))
+		errs = append(errs, validateVaryBy(routePath+".varyBy", rule.VaryBy)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateFields checks the address is set for redis stores.
+func (s *ThrottleStore) ValidateFields() error {
+	if s.Type == "redis" && len(s.Address) == 0 {
+		return ValidationErrors{{Path: "address", Message: "is required when type is redis"}}
+	}
+	return nil
+}
+
+func validateVaryBy(queryPath string, varyBy []string) ValidationErrors {
+	var errs ValidationErrors
+	for i, key := range varyBy {
+		switch {
+		case key == "remoteAddr", key == "method", key == "path":
+		case strings.HasPrefix(key, "header:") && len(strings.TrimPrefix(key, "header:")) > 0:
+		default:
+			errs = append(errs, &FieldError{
+				Path:    fmt.Sprintf("%s.%d", queryPath, i),
+				Message: fmt.Sprintf("must be remoteAddr, method, path or header:<name>, got %q", key),
+			})
+		}
+	}
+	return errs
+}
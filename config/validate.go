@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldsValidator can be implemented by configuration structs with rules that
+// can't be expressed with struct tags (e.g. fields that are only required when
+// another field is set). It's invoked by ValidateStruct for the struct itself
+// and every nested or embedded struct.
+type FieldsValidator interface {
+	// ValidateFields returns any violations, ideally as ValidationErrors with
+	// paths relative to the struct.
+	ValidateFields() error
+}
+
+// FieldError is a single validation violation.
+type FieldError struct {
+	Path    string // Json query path of the field (e.g. "http.port")
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors aggregates all violations found while validating.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(messages, "; "))
+}
+
+// ValidateStruct validates a configuration struct against the `validate`
+// struct tags of its fields (recursing into nested structs) and returns all
+// violations as ValidationErrors. Rules are comma separated:
+//
+//	required      must not be the zero value
+//	min=<n>       minimum value for numbers, minimum length otherwise
+//	max=<n>       maximum value for numbers, maximum length otherwise
+//	oneof=<a b>   must be one of the space separated values
+//	url           must be an absolute url
+//	file          must be the path of an existing file
+//	port          must be a tcp port (1-65535)
+//
+// Rules other than required are skipped for zero values, so combine them with
+// required when the field must be set.
+func ValidateStruct(s interface{}) error {
+	v := reflect.ValueOf(s)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("can only validate structs, got %T", s)
+	}
+	if !v.CanAddr() {
+		addressable := reflect.New(v.Type()).Elem()
+		addressable.Set(v)
+		v = addressable
+	}
+
+	var errs ValidationErrors
+	validateStruct(v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, queryPath string, errs *ValidationErrors) {
+	for _, f := range jsonFields(v.Type()) {
+		field := v.FieldByIndex(f.Index)
+		fieldPath := f.path(queryPath)
+
+		for _, rule := range splitRules(f.Tag.Get("validate")) {
+			message := checkRule(field, rule)
+			if len(message) > 0 {
+				*errs = append(*errs, &FieldError{Path: fieldPath, Message: message})
+			}
+		}
+
+		if _, ok := nestedStruct(f.Type); !ok {
+			continue
+		}
+		if field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		validateStruct(field, fieldPath, errs)
+	}
+
+	// Embedded structs share the path of their parent, so a FieldsValidator
+	// promoted from one reports the same errors twice (appendFieldsErrors
+	// drops the duplicates).
+	if !v.CanAddr() || !v.Addr().CanInterface() {
+		return
+	}
+	if validator, ok := v.Addr().Interface().(FieldsValidator); ok {
+		appendFieldsErrors(errs, queryPath, validator.ValidateFields())
+	}
+}
+
+// appendFieldsErrors adds errors from a FieldsValidator, prefixing their paths
+// with the path of the struct. Errors that were already reported are skipped.
+func appendFieldsErrors(errs *ValidationErrors, queryPath string, err error) {
+	if err == nil {
+		return
+	}
+	var fieldsErrs ValidationErrors
+	if !errors.As(err, &fieldsErrs) {
+		errs.appendUnique(&FieldError{Path: queryPath, Message: err.Error()})
+		return
+	}
+	for _, fieldErr := range fieldsErrs {
+		errs.appendUnique(&FieldError{Path: joinPath(queryPath, fieldErr.Path), Message: fieldErr.Message})
+	}
+}
+
+func (e *ValidationErrors) appendUnique(fieldErr *FieldError) {
+	for _, existing := range *e {
+		if *existing == *fieldErr {
+			return
+		}
+	}
+	*e = append(*e, fieldErr)
+}
+
+func splitRules(tag string) []string {
+	var rules []string
+	for _, rule := range strings.Split(tag, ",") {
+		rule = strings.TrimSpace(rule)
+		if len(rule) > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// checkRule returns a violation message if the field breaks the rule.
+func checkRule(field reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	if name == "required" {
+		if field.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+	if field.IsZero() {
+		return ""
+	}
+
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("has an invalid %s rule %q", name, arg)
+		}
+		value, isLength := numericValue(field)
+		qualifier := ""
+		if isLength {
+			qualifier = "a length of "
+		}
+		if name == "min" && value < limit {
+			return fmt.Sprintf("must have %sat least %s", qualifier, arg)
+		}
+		if name == "max" && value > limit {
+			return fmt.Sprintf("must have %sat most %s", qualifier, arg)
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		value := fmt.Sprintf("%v", field)
+		for _, option := range options {
+			if value == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(options, ", "))
+	case "url":
+		u, err := url.Parse(field.String())
+		if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return "must be an absolute url"
+		}
+	case "file":
+		return checkFile(field.String())
+	case "port":
+		value, _ := numericValue(field)
+		if value < 1 || value > 65535 {
+			return "must be a port between 1 and 65535"
+		}
+	default:
+		return fmt.Sprintf("has an unknown validation rule %q", name)
+	}
+	return ""
+}
+
+// checkFile returns a message if the path isn't an existing file.
+func checkFile(path string) string {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return fmt.Sprintf("file %s does not exist", path)
+	}
+	return ""
+}
+
+// numericValue returns the field as a number, or its length (and true) for
+// strings, slices, maps and arrays.
+func numericValue(field reflect.Value) (float64, bool) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), false
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(field.Len()), true
+	}
+	return 0, false
+}
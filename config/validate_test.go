@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateStructFieldsValidator(t *testing.T) {
+	type embedded struct {
+		Http
+		Name string `json:"name"`
+	}
+	type named struct {
+		Http Http `json:"http"`
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []FieldError
+	}{
+		{
+			name:  "embedded",
+			value: &embedded{Http: Http{TLSEnable: true}},
+			want: []FieldError{
+				{Path: "tlsCRT", Message: "is required when tlsEnable is set"},
+				{Path: "tlsKey", Message: "is required when tlsEnable is set"},
+			},
+		},
+		{
+			name:  "named",
+			value: &named{Http: Http{TLSEnable: true}},
+			want: []FieldError{
+				{Path: "http.tlsCRT", Message: "is required when tlsEnable is set"},
+				{Path: "http.tlsKey", Message: "is required when tlsEnable is set"},
+			},
+		},
+		{
+			name:  "root",
+			value: &Http{TLSEnable: true},
+			want: []FieldError{
+				{Path: "tlsCRT", Message: "is required when tlsEnable is set"},
+				{Path: "tlsKey", Message: "is required when tlsEnable is set"},
+			},
+		},
+		{
+			name:  "root by value",
+			value: Http{TLSEnable: true},
+			want: []FieldError{
+				{Path: "tlsCRT", Message: "is required when tlsEnable is set"},
+				{Path: "tlsKey", Message: "is required when tlsEnable is set"},
+			},
+		},
+		{
+			name:  "valid",
+			value: &embedded{Http: Http{Port: 8080}},
+		},
+		{
+			name:  "tls off with missing files",
+			value: &Http{Port: 8080, TLSCRT: "/does/not/exist.crt", TLSKey: "/does/not/exist.key"},
+		},
+		{
+			name:  "tls on with missing files",
+			value: &Http{Port: 8080, TLSEnable: true, TLSCRT: "/does/not/exist.crt", TLSKey: "/does/not/exist.key"},
+			want: []FieldError{
+				{Path: "tlsCRT", Message: "file /does/not/exist.crt does not exist"},
+				{Path: "tlsKey", Message: "file /does/not/exist.key does not exist"},
+			},
+		},
+		{
+			name:  "tls on with existing files",
+			value: &Http{Port: 8080, TLSEnable: true, TLSCRT: "validate_test.go", TLSKey: "validate_test.go"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateStruct(test.value)
+			var errs ValidationErrors
+			if err != nil && !errors.As(err, &errs) {
+				t.Fatalf("got error %v, want ValidationErrors", err)
+			}
+			if len(errs) != len(test.want) {
+				t.Fatalf("got errors %v, want %v", errs, test.want)
+			}
+			for i, fieldErr := range errs {
+				if *fieldErr != test.want[i] {
+					t.Errorf("got error %v, want %v", fieldErr, &test.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/derezzolution/platform/metrics"
+	"github.com/derezzolution/platform/service"
+	"github.com/gorilla/mux"
+)
+
+// NewAdminServer creates a server listening on httpConfig.AdminPort that
+// exposes the admin routes (see InitializeAdminRoutes). Unlike NewServer, the
+// admin server has its own handler and skips the core middleware so load
+// balancer health checks are never throttled. When metrics are enabled, they're
+// served here too. NewServerWithOptions creates and serves it when AdminEnable
+// and ServerOptions.Service are set, so this is only needed to run the admin
+// server on its own.
+func NewAdminServer(name string, httpConfig *config.Http, s *service.Service) *Server {
+	adminConfig := *httpConfig
+	adminConfig.Port = httpConfig.AdminPort
+
+	r := mux.NewRouter()
+	InitializeAdminRoutes(r, s)
+	if httpConfig.MetricsEnable {
+		r.Handle(httpConfig.MetricsRoute(), metrics.Handler()).Methods("GET")
+	}
+
+	server := &Server{
+		config: &adminConfig,
+		server: newHttpServer(&adminConfig),
+		name:   fmt.Sprintf("%s-admin", name),
+		logger: slog.Default().With("server", fmt.Sprintf("%s-admin", name), "port", adminConfig.Port),
+	}
+	server.server.Handler = r
+	return server
+}
+
+// InitializeAdminRoutes adds the admin routes to the given router:
+//
+//	/healthz  200 while the process is up
+//	/readyz   200 until the service receives a termination signal, then 503
+//	/version  the service version as json
+//	/runners  the status of all runners as json
+func InitializeAdminRoutes(r *mux.Router, s *service.Service) {
+	r.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		writeText(w, http.StatusOK, "ok")
+	}).Methods("GET", "HEAD")
+
+	r.HandleFunc("/readyz", func(w http.ResponseWriter, req *http.Request) {
+		if !s.IsReady() {
+			writeText(w, http.StatusServiceUnavailable, "shutting down")
+			return
+		}
+		writeText(w, http.StatusOK, "ok")
+	}).Methods("GET", "HEAD")
+
+	r.HandleFunc("/version", func(w http.ResponseWriter, req *http.Request) {
+		versionJson, err := s.Version.ToJson()
+		if err != nil {
+			writeText(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, versionJson)
+	}).Methods("GET")
+
+	r.HandleFunc("/runners", func(w http.ResponseWriter, req *http.Request) {
+		statusesJson, err := json.Marshal(s.RunnerStatuses())
+		if err != nil {
+			writeText(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, string(statusesJson))
+	}).Methods("GET")
+}
+
+func writeText(w http.ResponseWriter, statusCode int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	fmt.Fprintln(w, body)
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/justinas/alice"
+)
+
+// NewAccessLogHandler logs each request to the named server with its client
+// IP (see NewClientIPHandler), status, size and duration.
+func NewAccessLogHandler(server string) alice.Constructor {
+	logger := slog.Default().With("server", server)
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m := httpsnoop.CaptureMetrics(h, w, r)
+			logger.Info("request",
+				"clientIP", ClientIP(r),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", m.Code,
+				"bytes", m.Written,
+				"duration", m.Duration,
+				"userAgent", r.UserAgent())
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/justinas/alice"
+)
+
+type clientIPContextKey struct{}
+
+// NewClientIPHandler resolves the IP of the client behind any trusted proxies
+// and attaches it to the request context (see ClientIP). Only the given header
+// (X-Forwarded-For, Forwarded or X-Real-IP) is read, and only when the request
+// comes from a trusted proxy, and then only up to the first untrusted hop, so
+// clients can't spoof their address by sending the headers themselves.
+func NewClientIPHandler(trustedProxies []netip.Prefix, header string) alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr, ok := resolveClientIP(r, trustedProxies, header)
+			if ok {
+				r = r.WithContext(context.WithValue(r.Context(), clientIPContextKey{}, addr))
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIPFromContext returns the client IP resolved by the client IP handler.
+func ClientIPFromContext(ctx context.Context) (netip.Addr, bool) {
+	addr, ok := ctx.Value(clientIPContextKey{}).(netip.Addr)
+	return addr, ok
+}
+
+// ClientIP returns the resolved client IP of the request, falling back to the
+// host of RemoteAddr when the client IP handler isn't installed.
+func ClientIP(r *http.Request) string {
+	if addr, ok := ClientIPFromContext(r.Context()); ok {
+		return addr.String()
+	}
+	return remoteHost(r)
+}
+
+func resolveClientIP(r *http.Request, trustedProxies []netip.Prefix, header string) (netip.Addr, bool) {
+	client, ok := parseForwardedIP(remoteHost(r))
+	if !ok || !isTrustedProxy(client, trustedProxies) {
+		return client, ok
+	}
+
+	// Walk the hops from the closest proxy outwards, stopping at the first one
+	// we don't trust (or can't parse).
+	hops := forwardedHops(r, header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseForwardedIP(hops[i])
+		if !ok {
+			break
+		}
+		client = addr
+		if !isTrustedProxy(addr, trustedProxies) {
+			break
+		}
+	}
+	return client, true
+}
+
+// forwardedHops returns the client and proxy addresses reported by the
+// header, in the order they were added.
+func forwardedHops(r *http.Request, header string) []string {
+	values := r.Header.Values(header)
+	if len(values) == 0 {
+		return nil
+	}
+	if !strings.EqualFold(header, "Forwarded") {
+		return strings.Split(strings.Join(values, ","), ",")
+	}
+
+	var hops []string
+	for _, element := range strings.Split(strings.Join(values, ","), ",") {
+		hop := ""
+		for _, pair := range strings.Split(element, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			if strings.EqualFold(key, "for") {
+				hop = value
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// parseForwardedIP parses an address from a forwarding header, which may be
+// quoted and may include a port (e.g. "[2001:db8::1]:4711"). Obfuscated
+// identifiers and "unknown" aren't addresses.
+func parseForwardedIP(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrustedProxy(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
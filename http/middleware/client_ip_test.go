@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/justinas/alice"
+)
+
+func TestClientIP(t *testing.T) {
+	trustedProxies := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.1/32"),
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{
+			name:       "untrusted remote ignores headers",
+			header:     "X-Forwarded-For",
+			remoteAddr: "1.2.3.4:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"9.9.9.9"}},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "no header uses remote",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			want:       "10.0.0.5",
+		},
+		{
+			name:       "stops at first untrusted hop",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"9.9.9.9, 8.8.8.8, 10.1.1.1"}},
+			want:       "8.8.8.8",
+		},
+		{
+			name:       "combines repeated headers",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"9.9.9.9", "8.8.8.8"}},
+			want:       "8.8.8.8",
+		},
+		{
+			name:       "unparseable hop stops at the proxy that added it",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"garbage, 10.1.1.1"}},
+			want:       "10.1.1.1",
+		},
+		{
+			name:       "all hops trusted uses the leftmost",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.3.3.3, 10.1.1.1"}},
+			want:       "10.3.3.3",
+		},
+		{
+			name:       "spoofed Forwarded is ignored when proxies set X-Forwarded-For",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=6.6.6.6"},
+				"X-Forwarded-For": {"5.5.5.5"},
+			},
+			want: "5.5.5.5",
+		},
+		{
+			name:       "spoofed X-Real-IP is ignored when proxies set X-Forwarded-For",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			headers: map[string][]string{
+				"X-Real-IP":       {"6.6.6.6"},
+				"X-Forwarded-For": {"5.5.5.5"},
+			},
+			want: "5.5.5.5",
+		},
+		{
+			name:       "spoofed Forwarded without X-Forwarded-For uses remote",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			headers:    map[string][]string{"Forwarded": {"for=6.6.6.6"}},
+			want:       "10.0.0.5",
+		},
+		{
+			name:       "spoofed leftmost X-Forwarded-For is skipped",
+			header:     "X-Forwarded-For",
+			remoteAddr: "10.0.0.5:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"6.6.6.6, 5.5.5.5"}},
+			want:       "5.5.5.5",
+		},
+		{
+			name:       "Forwarded with quoted ipv6 and port",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.5:1234",
+			headers: map[string][]string{
+				"Forwarded":       {`for="[2001:db8::1]:4711";proto=https, for=10.2.2.2`},
+				"X-Forwarded-For": {"7.7.7.7"},
+			},
+			want: "2001:db8::1",
+		},
+		{
+			name:       "Forwarded unknown stops at the proxy",
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.5:1234",
+			headers:    map[string][]string{"Forwarded": {"for=unknown"}},
+			want:       "10.0.0.5",
+		},
+		{
+			name:       "spoofed X-Forwarded-For is ignored when proxies set X-Real-IP",
+			header:     "X-Real-IP",
+			remoteAddr: "192.168.1.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"6.6.6.6"},
+				"X-Real-IP":       {"5.5.5.5"},
+			},
+			want: "5.5.5.5",
+		},
+		{
+			name:       "ipv4 mapped ipv6 remote is trusted",
+			header:     "X-Forwarded-For",
+			remoteAddr: "[::ffff:10.0.0.5]:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"5.5.5.5"}},
+			want:       "5.5.5.5",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+			h := NewClientIPHandler(trustedProxies, test.header)(http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					got = ClientIP(r)
+				}))
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = test.remoteAddr
+			for key, values := range test.headers {
+				for _, value := range values {
+					r.Header.Add(key, value)
+				}
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != test.want {
+				t.Errorf("got client ip %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutHandler(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "1.2.3.4:1234"
+	if got := ClientIP(r); got != "1.2.3.4" {
+		t.Errorf("got client ip %q, want %q", got, "1.2.3.4")
+	}
+}
+
+func TestClientIPSpoofedForwardedIsThrottled(t *testing.T) {
+	trustedProxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32")}
+	throttle := &config.Throttle{ThrottleRule: config.ThrottleRule{Limit: 2}}
+	h := alice.New(NewClientIPHandler(trustedProxies, "X-Forwarded-For"), NewThrottleHandler("test", throttle, nil)).
+		Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var codes []int
+	for i := 0; i < 4; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", "5.5.5.5")
+		r.Header.Set("Forwarded", fmt.Sprintf("for=6.6.6.%d", i))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	if fmt.Sprint(codes) != fmt.Sprint(want) {
+		t.Errorf("got status codes %v, want %v", codes, want)
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/gorilla/handlers"
+	"github.com/justinas/alice"
+)
+
+// NewCorsHandler applies the configured CORS policy, using the router
+// override with the longest path prefix matching the request when there is
+// one. Origins are echoed back (with Vary: Origin) unless the policy allows
+// any origin.
+func NewCorsHandler(corsConfig *config.Cors) alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		defaultHandler := newCorsPolicyHandler(corsConfig.CorsPolicy.WithDefaults(), h)
+		if len(corsConfig.Routers) == 0 {
+			return defaultHandler
+		}
+
+		prefixes := make([]string, 0, len(corsConfig.Routers))
+		routerHandlers := make(map[string]http.Handler, len(corsConfig.Routers))
+		for prefix, policy := range corsConfig.Routers {
+			prefixes = append(prefixes, prefix)
+			routerHandlers[prefix] = newCorsPolicyHandler(policy.Inherit(corsConfig.CorsPolicy).WithDefaults(), h)
+		}
+		sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range prefixes {
+				if hasPathPrefix(r.URL.Path, prefix) {
+					routerHandlers[prefix].ServeHTTP(w, r)
+					return
+				}
+			}
+			defaultHandler.ServeHTTP(w, r)
+		})
+	}
+}
+
+func newCorsPolicyHandler(policy config.CorsPolicy, h http.Handler) http.Handler {
+	if policy.IsDisabled() {
+		return h
+	}
+
+	options := []handlers.CORSOption{
+		handlers.AllowedMethods(policy.AllowedMethods),
+		handlers.AllowedHeaders(policy.AllowedHeaders),
+		handlers.ExposedHeaders(policy.ExposedHeaders),
+		handlers.MaxAge(policy.MaxAge),
+	}
+	if policy.AllowsCredentials() {
+		options = append(options, handlers.AllowCredentials())
+	}
+	if policy.AllowsAnyOrigin() {
+		return handlers.CORS(options...)(h)
+	}
+
+	options = append(options, handlers.AllowedOriginValidator(func(origin string) bool {
+		return isOriginAllowed(origin, policy.AllowedOrigins)
+	}))
+	corsHandler := handlers.CORS(options...)(h)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The response depends on the origin so caches must key on it.
+		w.Header().Add("Vary", "Origin")
+		corsHandler.ServeHTTP(w, r)
+	})
+}
+
+// isOriginAllowed matches an origin against exact origins and wildcard
+// subdomain patterns (e.g. "https://*.example.com" matches
+// "https://api.example.com" but not "https://example.com").
+func isOriginAllowed(origin string, allowedOrigins []string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowedOrigin := range allowedOrigins {
+		allowedOrigin = strings.ToLower(strings.TrimSuffix(allowedOrigin, "/"))
+		if origin == allowedOrigin {
+			return true
+		}
+
+		scheme, domain, ok := strings.Cut(allowedOrigin, "://*.")
+		if !ok {
+			continue
+		}
+		prefix, suffix := scheme+"://", "."+domain
+		if !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+			continue
+		}
+		subdomain := strings.TrimSuffix(strings.TrimPrefix(origin, prefix), suffix)
+		if len(subdomain) > 0 && !strings.ContainsAny(subdomain, "/:@") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix returns whether the path is the prefix or below it (so "/api"
+// matches "/api/users" but not "/apis").
+func hasPathPrefix(path string, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/") || prefix == ""
+}
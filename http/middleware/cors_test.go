@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derezzolution/platform/config"
+)
+
+func TestIsOriginAllowed(t *testing.T) {
+	allowedOrigins := []string{"https://*.example.com", "https://app.other.io", "http://localhost:3000"}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.other.io", true},
+		{"https://APP.other.io", true},
+		{"https://api.example.com", true},
+		{"https://a.b.example.com", true},
+		{"http://localhost:3000", true},
+		{"https://example.com", false},
+		{"http://api.example.com", false},
+		{"https://api.example.com.evil.io", false},
+		{"https://evilexample.com", false},
+		{"https://evil.io/.example.com", false},
+		{"https://user@evil.io:.example.com", false},
+		{"https://evil.io:443.example.com", false},
+		{"http://localhost:3001", false},
+		{"https://other.io", false},
+		{"null", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.origin, func(t *testing.T) {
+			if got := isOriginAllowed(test.origin, allowedOrigins); got != test.want {
+				t.Errorf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   bool
+	}{
+		{"/api", "/api", true},
+		{"/api/users", "/api", true},
+		{"/api/users", "/api/", true},
+		{"/apis", "/api", false},
+		{"/anything", "/", true},
+	}
+
+	for _, test := range tests {
+		if got := hasPathPrefix(test.path, test.prefix); got != test.want {
+			t.Errorf("hasPathPrefix(%q, %q) got %t, want %t", test.path, test.prefix, got, test.want)
+		}
+	}
+}
+
+func TestCorsHandler(t *testing.T) {
+	corsConfig := &config.Cors{
+		CorsPolicy: config.CorsPolicy{
+			AllowedOrigins:   []string{"https://*.example.com"},
+			AllowCredentials: boolPointer(true),
+			Disable:          boolPointer(false),
+		},
+		Routers: map[string]config.CorsPolicy{
+			"/public":   {AllowCredentials: boolPointer(false)},
+			"/internal": {Disable: boolPointer(true)},
+		},
+	}
+	h := NewCorsHandler(corsConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name            string
+		path            string
+		origin          string
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{"allowed", "/api", "https://app.example.com", "https://app.example.com", "true"},
+		{"not allowed", "/api", "https://evil.io", "", ""},
+		{"router turns off credentials", "/public/x", "https://app.example.com", "https://app.example.com", ""},
+		{"router disables", "/internal/x", "https://app.example.com", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.path, nil)
+			r.Header.Set("Origin", test.origin)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != test.wantOrigin {
+				t.Errorf("got Access-Control-Allow-Origin %q, want %q", got, test.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != test.wantCredentials {
+				t.Errorf("got Access-Control-Allow-Credentials %q, want %q", got, test.wantCredentials)
+			}
+		})
+	}
+}
+
+func TestCorsHandlerRouterReEnables(t *testing.T) {
+	corsConfig := &config.Cors{
+		CorsPolicy: config.CorsPolicy{Disable: boolPointer(true)},
+		Routers:    map[string]config.CorsPolicy{"/public": {Disable: boolPointer(false)}},
+	}
+	h := NewCorsHandler(corsConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for path, want := range map[string]string{"/public/x": "*", "/private": ""} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("%s got Access-Control-Allow-Origin %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/derezzolution/platform/metrics"
+	"github.com/felixge/httpsnoop"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+// NewMetricsHandler records request counts, latencies and status codes for
+// the named server. Requests are labelled with the route template matched by
+// the router (e.g. "/users/{id}") rather than the raw path, so label
+// cardinality stays bounded; requests that don't match a route are labelled
+// "unmatched".
+func NewMetricsHandler(server string, router *mux.Router) alice.Constructor {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := "unmatched"
+			var match mux.RouteMatch
+			if router.Match(r, &match) && match.Route != nil {
+				if template, err := match.Route.GetPathTemplate(); err == nil {
+					route = template
+				}
+			}
+
+			m := httpsnoop.CaptureMetrics(h, w, r)
+			metrics.HttpRequestsTotal.WithLabelValues(server, route, r.Method, strconv.Itoa(m.Code)).Inc()
+			metrics.HttpRequestDuration.WithLabelValues(server, route, r.Method).Observe(m.Duration.Seconds())
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/derezzolution/platform/metrics"
+	"github.com/gorilla/mux"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	r := mux.NewRouter()
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	h := NewMetricsHandler("metrics-test", r)(r)
+
+	for _, path := range []string{"/users/1", "/users/2", "/nowhere"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+
+	want := []string{
+		`platform_http_requests_total{code="201",method="POST",route="/users/{id}",server="metrics-test"} 2`,
+		`platform_http_requests_total{code="404",method="POST",route="unmatched",server="metrics-test"} 1`,
+		`platform_http_request_duration_seconds_count{method="POST",route="/users/{id}",server="metrics-test"} 2`,
+	}
+	for _, line := range want {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics are missing %s", line)
+		}
+	}
+	if strings.Contains(string(body), "/users/1") {
+		t.Errorf("metrics are labelled with the raw path")
+	}
+}
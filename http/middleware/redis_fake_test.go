@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process server speaking enough of the Redis protocol
+// (RESP) for the throttle store: PING, AUTH, SELECT, TIME, GET, SET (with NX
+// and EX), SETNX, EXPIRE and EVAL of the compare-and-swap script. It can be
+// taken down (dropping connections) and brought back up on the same address.
+type fakeRedis struct {
+	listener net.Listener
+
+	mutex    sync.Mutex
+	data     map[string]string
+	expiries map[string]time.Time
+	conns    map[net.Conn]bool
+	isDown   bool
+	commands []string // Names of the commands received, in order
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{
+		listener: listener,
+		data:     map[string]string{},
+		expiries: map[string]time.Time{},
+		conns:    map[net.Conn]bool{},
+	}
+	go f.accept()
+	t.Cleanup(func() {
+		listener.Close()
+		f.setDown(true)
+	})
+	return f
+}
+
+func (f *fakeRedis) addr() string {
+	return f.listener.Addr().String()
+}
+
+// setDown drops all connections and refuses new ones while down.
+func (f *fakeRedis) setDown(isDown bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.isDown = isDown
+	if isDown {
+		for conn := range f.conns {
+			conn.Close()
+		}
+		f.conns = map[net.Conn]bool{}
+	}
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	value, ok := f.lookup(key)
+	return value, ok
+}
+
+func (f *fakeRedis) commandCount(name string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	n := 0
+	for _, command := range f.commands {
+		if command == name {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeRedis) accept() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.mutex.Lock()
+		if f.isDown {
+			conn.Close()
+		} else {
+			f.conns[conn] = true
+			go f.serve(conn)
+		}
+		f.mutex.Unlock()
+	}
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, f.execute(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as a RESP array of bulk strings.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("expected an array, got %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2) // Including the trailing \r\n
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) execute(args []string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(args) == 0 {
+		return "-ERR empty command\r\n"
+	}
+	name := strings.ToUpper(args[0])
+	f.commands = append(f.commands, name)
+
+	switch name {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "TIME":
+		now := time.Now()
+		return respArray(strconv.FormatInt(now.Unix(), 10), strconv.Itoa(now.Nanosecond()/1000))
+	case "GET":
+		if value, ok := f.lookup(args[1]); ok {
+			return respBulk(value)
+		}
+		return "$-1\r\n"
+	case "SETNX":
+		if _, ok := f.lookup(args[1]); ok {
+			return ":0\r\n"
+		}
+		f.set(args[1], args[2], 0)
+		return ":1\r\n"
+	case "SET":
+		isNX, ttl := false, time.Duration(0)
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				isNX = true
+			case "EX":
+				seconds, _ := strconv.Atoi(args[i+1])
+				ttl = time.Duration(seconds) * time.Second
+				i++
+			}
+		}
+		if _, ok := f.lookup(args[1]); ok && isNX {
+			return "$-1\r\n"
+		}
+		f.set(args[1], args[2], ttl)
+		return "+OK\r\n"
+	case "EXPIRE":
+		value, ok := f.lookup(args[1])
+		if !ok {
+			return ":0\r\n"
+		}
+		seconds, _ := strconv.Atoi(args[2])
+		f.set(args[1], value, time.Duration(seconds)*time.Second)
+		return ":1\r\n"
+	case "EVAL":
+		// Only the store's compare-and-swap script is supported:
+		// EVAL <script> 1 <key> <old> <new> <ttl seconds>
+		key, old, new := args[3], args[4], args[5]
+		value, ok := f.lookup(key)
+		if !ok {
+			return "-ERR key does not exist\r\n"
+		}
+		if value != old {
+			return ":0\r\n"
+		}
+		seconds, _ := strconv.Atoi(args[6])
+		f.set(key, new, time.Duration(seconds)*time.Second)
+		return ":1\r\n"
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+// lookup returns the value of an unexpired key (the mutex must be held).
+func (f *fakeRedis) lookup(key string) (string, bool) {
+	if expiry, ok := f.expiries[key]; ok && time.Now().After(expiry) {
+		delete(f.data, key)
+		delete(f.expiries, key)
+	}
+	value, ok := f.data[key]
+	return value, ok
+}
+
+// set stores a value, a zero ttl doesn't expire (the mutex must be held).
+func (f *fakeRedis) set(key string, value string, ttl time.Duration) {
+	f.data[key] = value
+	delete(f.expiries, key)
+	if ttl > 0 {
+		f.expiries[key] = time.Now().Add(ttl)
+	}
+}
+
+func respBulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func respArray(values ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(values))
+	for _, value := range values {
+		b.WriteString(respBulk(value))
+	}
+	return b.String()
+}
@@ -1,22 +1,184 @@
 package middleware
 
 import (
-	"github.com/throttled/throttled/v2"
-	"github.com/throttled/throttled/v2/store/memstore"
-
-	"log"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+	"github.com/throttled/throttled/v2"
 )
 
 // ThrottleHandler controls the number of requests that should be throttled to
-// the server.
+// the server (30 requests per minute per remote address).
+//
+// Deprecated: Use NewThrottleHandler, which is configured by config.Http.
 func ThrottleHandler(h http.Handler) http.Handler {
-	throttleStore, err := memstore.New(65536)
+	return NewThrottleHandler("", &config.Throttle{}, nil)(h)
+}
+
+// NewThrottleHandler rate limits requests to the named server as configured.
+// The router is used to match per route overrides by route template (nil
+// ignores overrides). Responses carry RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers and limited requests get a 429
+// with Retry-After. Counts are kept in memory unless a shared store is
+// configured; if the store fails, requests are let through rather than failing
+// the service.
+func NewThrottleHandler(server string, throttleConfig *config.Throttle, router *mux.Router) alice.Constructor {
+	logger := slog.Default().With("server", server)
+	passThrough := func(h http.Handler) http.Handler { return h }
+	if throttleConfig.IsDisabled() && len(throttleConfig.Routes) == 0 {
+		return passThrough
+	}
+
+	store, err := newThrottleStore(server, &throttleConfig.Store, logger)
 	if err != nil {
-		log.Fatal(err)
+		logger.Error("unable to create throttle store, requests won't be throttled", "error", err)
+		return passThrough
 	}
 
-	return throttled.RateLimit(throttled.PerMin(30),
-		&throttled.VaryBy{RemoteAddr: true},
-		throttleStore).Throttle(h)
+	defaultRule := throttleConfig.ThrottleRule.WithDefaults()
+	defaultLimiter, err := newRouteLimiter("", defaultRule, store)
+	if err != nil {
+		logger.Error("unable to create throttle, requests won't be throttled", "error", err)
+		return passThrough
+	}
+	routeLimiters := make(map[string]*routeLimiter, len(throttleConfig.Routes))
+	for route, rule := range throttleConfig.Routes {
+		route = normalizeThrottleRoute(route)
+		limiter, err := newRouteLimiter(route, rule.Inherit(throttleConfig.ThrottleRule).WithDefaults(), store)
+		if err != nil {
+			logger.Error("unable to create route throttle, using the default", "route", route, "error", err)
+			continue
+		}
+		routeLimiters[route] = limiter
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limiter := defaultLimiter
+			if len(routeLimiters) > 0 && router != nil {
+				limiter = matchRouteLimiter(router, r, routeLimiters, defaultLimiter)
+			}
+			if limiter == nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			limited, result, err := limiter.rateLimiter.RateLimit(limiter.key(r), 1)
+			if err != nil {
+				logger.Warn("unable to check rate limit, letting request through", "error", err)
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			setRateLimitHeaders(w, limiter.policy, result)
+			if limited {
+				http.Error(w, "limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeLimiter is the rate limiter for a route (or the default). A nil
+// routeLimiter means throttling is disabled for the route.
+type routeLimiter struct {
+	route       string
+	rateLimiter throttled.RateLimiter
+	varyBy      []string
+	policy      string // RateLimit-Policy header value
+}
+
+func newRouteLimiter(route string, rule config.ThrottleRule, store throttled.GCRAStore) (*routeLimiter, error) {
+	if rule.IsDisabled() {
+		return nil, nil
+	}
+	period := rule.PeriodDuration()
+	rateLimiter, err := throttled.NewGCRARateLimiter(store, throttled.RateQuota{
+		MaxRate:  throttled.PerDuration(rule.Limit, period),
+		MaxBurst: rule.Burst - 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &routeLimiter{
+		route:       route,
+		rateLimiter: rateLimiter,
+		varyBy:      rule.VaryBy,
+		policy:      fmt.Sprintf("%d;w=%d", rule.Limit, int(period.Seconds())),
+	}, nil
+}
+
+// key returns the store key for the request. Keys are prefixed with the route
+// so overrides don't share counts with the default.
+func (l *routeLimiter) key(r *http.Request) string {
+	parts := []string{l.route}
+	for _, varyBy := range l.varyBy {
+		switch {
+		case varyBy == "remoteAddr":
+			parts = append(parts, ClientIP(r))
+		case varyBy == "method":
+			parts = append(parts, r.Method)
+		case varyBy == "path":
+			parts = append(parts, r.URL.Path)
+		case strings.HasPrefix(varyBy, "header:"):
+			parts = append(parts, r.Header.Get(strings.TrimPrefix(varyBy, "header:")))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// matchRouteLimiter returns the limiter for the route matched by the router,
+// preferring overrides for the request method.
+func matchRouteLimiter(router *mux.Router, r *http.Request, routeLimiters map[string]*routeLimiter,
+	defaultLimiter *routeLimiter) *routeLimiter {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return defaultLimiter
+	}
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return defaultLimiter
+	}
+	if limiter, ok := routeLimiters[r.Method+" "+template]; ok {
+		return limiter
+	}
+	if limiter, ok := routeLimiters[template]; ok {
+		return limiter
+	}
+	return defaultLimiter
+}
+
+// normalizeThrottleRoute tidies up the spacing and method case of a route key
+// (e.g. "post  /login" becomes "POST /login").
+func normalizeThrottleRoute(route string) string {
+	fields := strings.Fields(route)
+	if len(fields) == 2 {
+		return strings.ToUpper(fields[0]) + " " + fields[1]
+	}
+	return strings.TrimSpace(route)
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, policy string, result throttled.RateLimitResult) {
+	header := w.Header()
+	header.Set("RateLimit-Policy", policy)
+	if result.Limit >= 0 {
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	}
+	if result.Remaining >= 0 {
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	}
+	if result.ResetAfter >= 0 {
+		header.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+	}
+	if result.RetryAfter >= 0 {
+		header.Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+	}
 }
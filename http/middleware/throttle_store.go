@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/gomodule/redigo/redis"
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/memstore"
+	"github.com/throttled/throttled/v2/store/redigostore"
+)
+
+const (
+	// throttleStoreSize is the number of keys the in-memory store tracks
+	// before evicting the least recently used.
+	throttleStoreSize = 65536
+
+	// redisTimeout bounds how long a request can be held up by a slow or
+	// unreachable Redis server before falling back to memory.
+	redisTimeout = 500 * time.Millisecond
+
+	// redisRetryInterval is how long to stay on the fallback store before
+	// trying the Redis server again.
+	redisRetryInterval = 10 * time.Second
+)
+
+// newThrottleStore creates the store configured for the named server.
+func newThrottleStore(server string, storeConfig *config.ThrottleStore, logger *slog.Logger) (throttled.GCRAStore,
+	error) {
+	memoryStore, err := memstore.New(throttleStoreSize)
+	if err != nil {
+		return nil, err
+	}
+	if storeConfig.Type != "redis" {
+		return memoryStore, nil
+	}
+
+	keyPrefix := storeConfig.KeyPrefix
+	if len(keyPrefix) == 0 {
+		keyPrefix = "throttle:"
+	}
+	redisStore, err := redigostore.New(newRedisPool(storeConfig), fmt.Sprintf("%s%s:", keyPrefix, server),
+		storeConfig.DB)
+	if err != nil {
+		return nil, err
+	}
+	return &fallbackStore{
+		primary:       redisStore,
+		fallback:      memoryStore,
+		retryInterval: redisRetryInterval,
+		logger:        logger.With("store", storeConfig.Address),
+	}, nil
+}
+
+func newRedisPool(storeConfig *config.ThrottleStore) *redis.Pool {
+	options := []redis.DialOption{
+		redis.DialConnectTimeout(redisTimeout),
+		redis.DialReadTimeout(redisTimeout),
+		redis.DialWriteTimeout(redisTimeout),
+	}
+	if len(storeConfig.Password) > 0 {
+		options = append(options, redis.DialPassword(storeConfig.Password))
+	}
+
+	return &redis.Pool{
+		MaxIdle:     16,
+		IdleTimeout: 4 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			if strings.HasPrefix(storeConfig.Address, "redis://") ||
+				strings.HasPrefix(storeConfig.Address, "rediss://") {
+				return redis.DialURL(storeConfig.Address, options...)
+			}
+			return redis.Dial("tcp", storeConfig.Address, options...)
+		},
+		TestOnBorrow: func(c redis.Conn, idleSince time.Time) error {
+			if time.Since(idleSince) < time.Minute {
+				return nil
+			}
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
+
+// fallbackStore uses the primary store until it fails and then the fallback
+// store until retryInterval has passed, so an unreachable shared store
+// degrades to per-instance limits instead of failing requests.
+type fallbackStore struct {
+	primary       throttled.GCRAStore
+	fallback      throttled.GCRAStore
+	retryInterval time.Duration
+	logger        *slog.Logger
+
+	mutex    sync.Mutex
+	failedAt time.Time // Zero while the primary store is healthy
+}
+
+func (s *fallbackStore) GetWithTime(key string) (int64, time.Time, error) {
+	if s.isPrimaryAvailable() {
+		value, now, err := s.primary.GetWithTime(key)
+		if err == nil {
+			s.recordSuccess()
+			return value, now, nil
+		}
+		s.recordFailure(err)
+	}
+	return s.fallback.GetWithTime(key)
+}
+
+func (s *fallbackStore) SetIfNotExistsWithTTL(key string, value int64, ttl time.Duration) (bool, error) {
+	if s.isPrimaryAvailable() {
+		updated, err := s.primary.SetIfNotExistsWithTTL(key, value, ttl)
+		if err == nil {
+			s.recordSuccess()
+			return updated, nil
+		}
+		s.recordFailure(err)
+	}
+	return s.fallback.SetIfNotExistsWithTTL(key, value, ttl)
+}
+
+func (s *fallbackStore) CompareAndSwapWithTTL(key string, old, new int64, ttl time.Duration) (bool, error) {
+	if s.isPrimaryAvailable() {
+		swapped, err := s.primary.CompareAndSwapWithTTL(key, old, new, ttl)
+		if err == nil {
+			s.recordSuccess()
+			return swapped, nil
+		}
+		s.recordFailure(err)
+	}
+	return s.fallback.CompareAndSwapWithTTL(key, old, new, ttl)
+}
+
+func (s *fallbackStore) isPrimaryAvailable() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.failedAt.IsZero() || time.Since(s.failedAt) >= s.retryInterval
+}
+
+func (s *fallbackStore) recordSuccess() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.failedAt.IsZero() {
+		s.logger.Info("throttle store is reachable again")
+		s.failedAt = time.Time{}
+	}
+}
+
+func (s *fallbackStore) recordFailure(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failedAt.IsZero() {
+		s.logger.Warn("throttle store failed, falling back to in-memory counts", "error", err)
+	}
+	s.failedAt = time.Now()
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/throttled/throttled/v2"
+	"github.com/throttled/throttled/v2/store/memstore"
+)
+
+func redisThrottleConfig(addr string, limit int) *config.Throttle {
+	return &config.Throttle{
+		ThrottleRule: config.ThrottleRule{Limit: limit},
+		Store:        config.ThrottleStore{Type: "redis", Address: addr},
+	}
+}
+
+func newTestFallbackStore(t *testing.T, addr string, retryInterval time.Duration) *fallbackStore {
+	store, err := newThrottleStore("test", &redisThrottleConfig(addr, 0).Store, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback, ok := store.(*fallbackStore)
+	if !ok {
+		t.Fatalf("got store %T, want *fallbackStore", store)
+	}
+	fallback.retryInterval = retryInterval
+	return fallback
+}
+
+func rateLimit(t *testing.T, limiter throttled.RateLimiter, key string) bool {
+	limited, _, err := limiter.RateLimit(key, 1)
+	if err != nil {
+		t.Fatalf("unexpected rate limit error: %s", err)
+	}
+	return limited
+}
+
+func newTestRateLimiter(t *testing.T, store throttled.GCRAStore, limit int) throttled.RateLimiter {
+	limiter, err := throttled.NewGCRARateLimiter(store, throttled.RateQuota{
+		MaxRate:  throttled.PerMin(limit),
+		MaxBurst: limit - 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return limiter
+}
+
+func TestRedisThrottleStoreSharesCounts(t *testing.T) {
+	fake := newFakeRedis(t)
+	throttleConfig := redisThrottleConfig(fake.addr(), 2)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	instances := []http.Handler{
+		NewThrottleHandler("test", throttleConfig, nil)(ok),
+		NewThrottleHandler("test", throttleConfig, nil)(ok),
+	}
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		instances[i%2].ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		codes = append(codes, w.Code)
+	}
+
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	if fmt.Sprint(codes) != fmt.Sprint(want) {
+		t.Errorf("got status codes %v, want %v", codes, want)
+	}
+	if _, ok := fake.get("throttle:test:\n192.0.2.1"); !ok {
+		t.Error("expected the count to be stored in redis")
+	}
+}
+
+func TestFallbackStoreFailover(t *testing.T) {
+	fake := newFakeRedis(t)
+	store := newTestFallbackStore(t, fake.addr(), time.Hour)
+	limiter := newTestRateLimiter(t, store, 1)
+
+	if rateLimit(t, limiter, "a") {
+		t.Fatal("first request on the primary store was limited")
+	}
+
+	fake.setDown(true)
+	if rateLimit(t, limiter, "b") {
+		t.Error("first request on the fallback store was limited")
+	}
+	if !rateLimit(t, limiter, "b") {
+		t.Error("second request on the fallback store wasn't limited")
+	}
+	if store.isPrimaryAvailable() {
+		t.Error("primary store is available after failing")
+	}
+}
+
+func TestFallbackStoreRecovery(t *testing.T) {
+	fake := newFakeRedis(t)
+	store := newTestFallbackStore(t, fake.addr(), 100*time.Millisecond)
+	limiter := newTestRateLimiter(t, store, 10)
+
+	fake.setDown(true)
+	rateLimit(t, limiter, "a")
+	fake.setDown(false)
+
+	// The primary store isn't retried until the retry interval has passed.
+	gets := fake.commandCount("GET")
+	rateLimit(t, limiter, "a")
+	if got := fake.commandCount("GET"); got != gets {
+		t.Errorf("primary store was retried before the retry interval (%d GETs, want %d)", got, gets)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	rateLimit(t, limiter, "a")
+	if got := fake.commandCount("GET"); got == gets {
+		t.Error("primary store wasn't retried after the retry interval")
+	}
+	if _, ok := fake.get("throttle:test:a"); !ok {
+		t.Error("expected the count to be stored in redis after recovering")
+	}
+	if !store.failedAt.IsZero() {
+		t.Error("primary store is still marked as failed after recovering")
+	}
+}
+
+// failingSwapStore is a store whose compare-and-swap fails, like a primary
+// store becoming unreachable partway through a rate limit check.
+type failingSwapStore struct {
+	throttled.GCRAStore
+}
+
+func (s *failingSwapStore) CompareAndSwapWithTTL(key string, old, new int64, ttl time.Duration) (bool, error) {
+	return false, errors.New("connection reset")
+}
+
+func TestFallbackStoreCompareAndSwapFailsPartway(t *testing.T) {
+	primary, err := memstore.New(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback, err := memstore.New(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &fallbackStore{
+		primary:       &failingSwapStore{primary},
+		fallback:      fallback,
+		retryInterval: time.Hour,
+		logger:        slog.Default(),
+	}
+	limiter := newTestRateLimiter(t, store, 2)
+
+	// The first request sets the key on the primary store, the second reads
+	// it from the primary store and then fails to swap it.
+	for i := 0; i < 2; i++ {
+		if rateLimit(t, limiter, "a") {
+			t.Fatalf("request %d was limited", i+1)
+		}
+	}
+	if store.isPrimaryAvailable() {
+		t.Error("primary store is available after a failed swap")
+	}
+
+	// Counting continues on the fallback store.
+	if rateLimit(t, limiter, "a") {
+		t.Error("first request counted on the fallback store was limited")
+	}
+	if !rateLimit(t, limiter, "a") {
+		t.Error("fallback store doesn't enforce the limit")
+	}
+}
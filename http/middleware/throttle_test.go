@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/gorilla/mux"
+)
+
+func boolPointer(b bool) *bool {
+	return &b
+}
+
+// throttleCodes sends the requests ("METHOD /path") through a throttled router
+// and returns the status codes.
+func throttleCodes(throttleConfig *config.Throttle, requests ...string) []int {
+	r := mux.NewRouter()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	r.Handle("/login", ok)
+	r.Handle("/users/{id}", ok)
+	h := NewThrottleHandler("test", throttleConfig, r)(r)
+
+	var codes []int
+	for _, request := range requests {
+		var method, path string
+		fmt.Sscan(request, &method, &path)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+func TestThrottleHandler(t *testing.T) {
+	const (
+		ok      = http.StatusOK
+		limited = http.StatusTooManyRequests
+	)
+
+	tests := []struct {
+		name     string
+		config   config.Throttle
+		requests []string
+		want     []int
+	}{
+		{
+			name:     "default limit",
+			config:   config.Throttle{ThrottleRule: config.ThrottleRule{Limit: 2}},
+			requests: []string{"GET /users/1", "GET /users/2", "GET /users/3"},
+			want:     []int{ok, ok, limited},
+		},
+		{
+			name:     "disabled",
+			config:   config.Throttle{ThrottleRule: config.ThrottleRule{Limit: 1, Disable: boolPointer(true)}},
+			requests: []string{"GET /users/1", "GET /users/2"},
+			want:     []int{ok, ok},
+		},
+		{
+			name: "route override has its own count",
+			config: config.Throttle{
+				ThrottleRule: config.ThrottleRule{Limit: 2},
+				Routes:       map[string]config.ThrottleRule{"post /login": {Limit: 1}},
+			},
+			requests: []string{"POST /login", "POST /login", "GET /login", "GET /users/1", "GET /users/1"},
+			want:     []int{ok, limited, ok, ok, limited},
+		},
+		{
+			name: "route override disables",
+			config: config.Throttle{
+				ThrottleRule: config.ThrottleRule{Limit: 1},
+				Routes:       map[string]config.ThrottleRule{"/login": {Disable: boolPointer(true)}},
+			},
+			requests: []string{"GET /login", "GET /login", "GET /users/1", "GET /users/1"},
+			want:     []int{ok, ok, ok, limited},
+		},
+		{
+			name: "route override re-enables",
+			config: config.Throttle{
+				ThrottleRule: config.ThrottleRule{Limit: 1, Disable: boolPointer(true)},
+				Routes:       map[string]config.ThrottleRule{"/login": {Disable: boolPointer(false)}},
+			},
+			requests: []string{"GET /login", "GET /login", "GET /users/1", "GET /users/1"},
+			want:     []int{ok, limited, ok, ok},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			codes := throttleCodes(&test.config, test.requests...)
+			if fmt.Sprint(codes) != fmt.Sprint(test.want) {
+				t.Errorf("got status codes %v, want %v", codes, test.want)
+			}
+		})
+	}
+}
+
+func TestThrottleHandlerHeaders(t *testing.T) {
+	h := NewThrottleHandler("test", &config.Throttle{ThrottleRule: config.ThrottleRule{Limit: 1}}, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "1",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Policy":    "1;w=60",
+		"Retry-After":         "",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("got %s %q, want %q", header, got, want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusTooManyRequests || len(w.Header().Get("Retry-After")) == 0 {
+		t.Errorf("got status %d and Retry-After %q, want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+}
@@ -4,27 +4,73 @@ import (
 	ctx "context"
 	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/derezzolution/platform/config"
 	"github.com/derezzolution/platform/http/middleware"
+	"github.com/derezzolution/platform/metrics"
+	"github.com/derezzolution/platform/service"
 	"github.com/gorilla/context"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/justinas/alice"
 )
 
+// Names of the built-in middlewares (see ServerOptions).
+const (
+	MiddlewareClientIP  = "clientIP"
+	MiddlewareAccessLog = "accessLog" // Only installed when AccessLogEnable is set
+	MiddlewareMetrics   = "metrics"   // Only installed when MetricsEnable is set
+	MiddlewareThrottle  = "throttle"
+	MiddlewareCompress  = "compress"
+	MiddlewareCors      = "cors"
+)
+
+// DefaultMiddlewareOrder is the order the built-in middlewares wrap requests in
+// (outermost first).
+var DefaultMiddlewareOrder = []string{
+	MiddlewareClientIP,
+	MiddlewareAccessLog,
+	MiddlewareMetrics,
+	MiddlewareThrottle,
+	MiddlewareCompress,
+	MiddlewareCors,
+}
+
 type ServerOptions struct {
 	InitializeRoutesFunc func(r *mux.Router)
-	Middlware            []alice.Constructor
+
+	// Service backs the admin server's readiness, version and runner routes.
+	// When AdminEnable is set, the admin server is started and shut down along
+	// with this server (it's required for that).
+	Service *service.Service
+
+	// Middlware is appended after the built-in middlewares.
+	Middlware []alice.Constructor
+
+	// MiddlewareOrder lists the middlewares to install by name, outermost
+	// first (defaults to DefaultMiddlewareOrder). Names can be built-in or
+	// keys of ReplaceMiddleware.
+	MiddlewareOrder []string
+
+	// DisableMiddleware skips middlewares by name (e.g. "compress" for
+	// streaming responses).
+	DisableMiddleware []string
+
+	// ReplaceMiddleware swaps the built-in middleware of the same name (or
+	// adds a custom one that can be named in MiddlewareOrder).
+	ReplaceMiddleware map[string]alice.Constructor
 }
 
 type Server struct {
 	config *config.Http
 	server *http.Server
 	name   string // Name of server (used in logging)
+	logger *slog.Logger
+	admin  *Server // Admin server started alongside this one (nil when disabled)
 }
 
 func NewServer(name string, httpConfig *config.Http, initializeRoutesFunc func(r *mux.Router)) *Server {
@@ -38,14 +84,28 @@ func NewServerWithOptions(name string, httpConfig *config.Http, serverOptions *S
 		config: httpConfig,
 		server: newHttpServer(httpConfig),
 		name:   name,
+		logger: slog.Default().With("server", name, "port", httpConfig.Port),
 	}
-	http.Handle("/", createHttpHandler(serverOptions))
+	if httpConfig.AdminEnable {
+		if serverOptions.Service != nil {
+			server.admin = NewAdminServer(name, httpConfig, serverOptions.Service)
+		} else {
+			server.logger.Error("adminEnable is set but ServerOptions.Service is nil, not starting the admin server")
+		}
+	}
+
+	// Metrics are served by the admin server when there is one.
+	serveMetrics := httpConfig.MetricsEnable && server.admin == nil
+	http.Handle("/", createHttpHandler(server.fullName(), httpConfig, serverOptions, serveMetrics))
 	return server
 }
 
 // Serve is the entry-point for the http package. This takes a service, sets up
 // http server (as a function of the config) adds routes.
 func (s *Server) Serve() {
+	if s.admin != nil {
+		s.admin.Serve()
+	}
 	go func() {
 		s.Logf("started, listeners open")
 		var err error
@@ -70,6 +130,12 @@ func (s *Server) Shutdown() error {
 	}
 	s.Logf("shut down complete, open listners and active connections " +
 		"terminated")
+	if s.admin != nil {
+		adminErr := s.admin.Shutdown()
+		if err == nil {
+			err = adminErr
+		}
+	}
 	return err
 }
 
@@ -77,9 +143,14 @@ func (s *Server) fullName() string {
 	return fmt.Sprintf("%s-http[%d]", s.name, s.config.Port)
 }
 
+// Logger returns the server's structured logger (tagged with the server name
+// and port).
+func (s *Server) Logger() *slog.Logger {
+	return s.logger
+}
+
 func (s *Server) Logf(pattern string, args ...interface{}) {
-	log.Printf("%s: "+pattern,
-		append([]interface{}{s.fullName()}, args...)...)
+	s.logger.Info(fmt.Sprintf(pattern, args...))
 }
 
 // newHttpServer creates a new HTTP Server configured with TLS defaults.
@@ -112,15 +183,126 @@ func newHttpServer(config *config.Http) *http.Server {
 }
 
 // Creates a standard http handler with core middleware for all http services.
-func createHttpHandler(serverOptions *ServerOptions) http.Handler {
+func createHttpHandler(name string, httpConfig *config.Http, serverOptions *ServerOptions,
+	serveMetrics bool) http.Handler {
 	r := mux.NewRouter()
 	serverOptions.InitializeRoutesFunc(r)
-	return context.ClearHandler(
-		alice.New(
-			middleware.ThrottleHandler,
-			handlers.CompressHandler,
-			handlers.CORS(
-				handlers.AllowedMethods([]string{"OPTIONS", "DELETE", "GET", "HEAD", "POST", "PUT"}),
-				handlers.AllowedHeaders([]string{"Authorization", "Content-Type"}),
-			)).Append(serverOptions.Middlware...).Then(r))
+	if serveMetrics {
+		r.Handle(httpConfig.MetricsRoute(), metrics.Handler()).Methods("GET")
+	}
+
+	for _, err := range serverOptions.validateMiddlewareNames() {
+		slog.Error("ignoring middleware option", "server", name, "error", err)
+	}
+
+	chain := alice.New()
+	for _, middlewareName := range serverOptions.middlewareOrder() {
+		if serverOptions.isMiddlewareDisabled(middlewareName) {
+			continue
+		}
+		constructor, ok := serverOptions.ReplaceMiddleware[middlewareName]
+		if !ok {
+			constructor, ok = builtInMiddleware(middlewareName, name, httpConfig, r)
+		}
+		if !ok {
+			slog.Error("ignoring unknown middleware", "server", name, "middleware", middlewareName)
+			continue
+		}
+		if constructor != nil {
+			chain = chain.Append(constructor)
+		}
+	}
+	return context.ClearHandler(chain.Append(serverOptions.Middlware...).Then(r))
+}
+
+// builtInMiddleware returns the named built-in middleware and whether there is
+// one. The constructor is nil when the middleware is turned off by config.
+func builtInMiddleware(middlewareName string, name string, httpConfig *config.Http, r *mux.Router) (alice.Constructor,
+	bool) {
+	switch middlewareName {
+	case MiddlewareClientIP:
+		trustedProxies, err := httpConfig.TrustedProxyPrefixes()
+		if err != nil {
+			slog.Error("ignoring trusted proxies, forwarding headers won't be used", "server", name, "error", err)
+		}
+		return middleware.NewClientIPHandler(trustedProxies, httpConfig.ClientIPHeaderName()), true
+	case MiddlewareAccessLog:
+		if !httpConfig.AccessLogEnable {
+			return nil, true
+		}
+		return middleware.NewAccessLogHandler(name), true
+	case MiddlewareMetrics:
+		if !httpConfig.MetricsEnable {
+			return nil, true
+		}
+		return middleware.NewMetricsHandler(name, r), true
+	case MiddlewareThrottle:
+		return middleware.NewThrottleHandler(name, &httpConfig.Throttle, r), true
+	case MiddlewareCompress:
+		return handlers.CompressHandler, true
+	case MiddlewareCors:
+		return middleware.NewCorsHandler(&httpConfig.Cors), true
+	}
+	return nil, false
+}
+
+func (o *ServerOptions) middlewareOrder() []string {
+	if o.MiddlewareOrder != nil {
+		return o.MiddlewareOrder
+	}
+	return DefaultMiddlewareOrder
+}
+
+func (o *ServerOptions) isMiddlewareDisabled(name string) bool {
+	for _, disabled := range o.DisableMiddleware {
+		if disabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMiddlewareNames reports names in DisableMiddleware and
+// ReplaceMiddleware that don't match a middleware that would be installed, so
+// typos don't silently leave a middleware in place. Unknown names in
+// MiddlewareOrder are reported when the chain is built.
+func (o *ServerOptions) validateMiddlewareNames() []error {
+	order := o.middlewareOrder()
+	isKnown := func(name string) bool {
+		if _, ok := o.ReplaceMiddleware[name]; ok {
+			return true
+		}
+		for _, builtIn := range DefaultMiddlewareOrder {
+			if builtIn == name {
+				return true
+			}
+		}
+		return false
+	}
+	isOrdered := func(name string) bool {
+		for _, ordered := range order {
+			if ordered == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	var errs []error
+	for _, name := range o.DisableMiddleware {
+		if !isKnown(name) {
+			errs = append(errs, fmt.Errorf("can't disable unknown middleware %q", name))
+		}
+	}
+	replacedNames := make([]string, 0, len(o.ReplaceMiddleware))
+	for name := range o.ReplaceMiddleware {
+		replacedNames = append(replacedNames, name)
+	}
+	sort.Strings(replacedNames)
+	for _, name := range replacedNames {
+		if !isOrdered(name) {
+			errs = append(errs, fmt.Errorf("replacement middleware %q isn't in the middleware order", name))
+		}
+	}
+	return errs
 }
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/gorilla/mux"
+	"github.com/justinas/alice"
+)
+
+func TestValidateMiddlewareNames(t *testing.T) {
+	noop := func(h http.Handler) http.Handler { return h }
+
+	tests := []struct {
+		name    string
+		options ServerOptions
+		want    []string
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:    "built-in names",
+			options: ServerOptions{DisableMiddleware: []string{"compress"}, ReplaceMiddleware: map[string]alice.Constructor{"throttle": noop}},
+		},
+		{
+			name:    "typo in disable",
+			options: ServerOptions{DisableMiddleware: []string{"compression"}},
+			want:    []string{`can't disable unknown middleware "compression"`},
+		},
+		{
+			name:    "replacement not in order",
+			options: ServerOptions{ReplaceMiddleware: map[string]alice.Constructor{"throtle": noop}},
+			want:    []string{`replacement middleware "throtle" isn't in the middleware order`},
+		},
+		{
+			name: "custom middleware",
+			options: ServerOptions{
+				MiddlewareOrder:   []string{"custom", "cors"},
+				DisableMiddleware: []string{"custom"},
+				ReplaceMiddleware: map[string]alice.Constructor{"custom": noop},
+			},
+		},
+		{
+			name: "replaced built-in left out of custom order",
+			options: ServerOptions{
+				MiddlewareOrder:   []string{"cors"},
+				ReplaceMiddleware: map[string]alice.Constructor{"throttle": noop},
+			},
+			want: []string{`replacement middleware "throttle" isn't in the middleware order`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, err := range test.options.validateMiddlewareNames() {
+				got = append(got, err.Error())
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("got errors %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCreateHttpHandlerMiddleware(t *testing.T) {
+	body := strings.Repeat("streamed ", 100)
+	initializeRoutes := func(r *mux.Router) {
+		r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, body) })
+	}
+	tag := func(value string) alice.Constructor {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Chain", value)
+				h.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	tests := []struct {
+		name         string
+		options      ServerOptions
+		wantEncoding string
+		wantChain    string
+	}{
+		{
+			name:         "default chain compresses",
+			wantEncoding: "gzip",
+		},
+		{
+			name:    "compress disabled",
+			options: ServerOptions{DisableMiddleware: []string{MiddlewareCompress}},
+		},
+		{
+			name: "replaced and reordered",
+			options: ServerOptions{
+				MiddlewareOrder:   []string{"custom", MiddlewareThrottle},
+				ReplaceMiddleware: map[string]alice.Constructor{"custom": tag("custom"), MiddlewareThrottle: tag("throttle")},
+				Middlware:         []alice.Constructor{tag("appended")},
+			},
+			wantChain: "custom,throttle,appended",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			options.InitializeRoutesFunc = initializeRoutes
+			h := createHttpHandler("test", &config.Http{}, &options, false)
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Content-Encoding"); got != test.wantEncoding {
+				t.Errorf("got Content-Encoding %q, want %q", got, test.wantEncoding)
+			}
+			if got := strings.Join(w.Header().Values("X-Chain"), ","); got != test.wantChain {
+				t.Errorf("got chain %q, want %q", got, test.wantChain)
+			}
+		})
+	}
+}
+
+func TestBuiltInMiddlewareTurnedOffByConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware string
+		httpConfig config.Http
+		wantNil    bool
+	}{
+		{"access log off", MiddlewareAccessLog, config.Http{}, true},
+		{"access log on", MiddlewareAccessLog, config.Http{AccessLogEnable: true}, false},
+		{"metrics off", MiddlewareMetrics, config.Http{}, true},
+		{"metrics on", MiddlewareMetrics, config.Http{MetricsEnable: true}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			constructor, ok := builtInMiddleware(test.middleware, "test", &test.httpConfig, mux.NewRouter())
+			if !ok {
+				t.Fatalf("%s isn't a built-in middleware", test.middleware)
+			}
+			if got := constructor == nil; got != test.wantNil {
+				t.Errorf("got nil constructor %t, want %t", got, test.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds all platform metrics. Services can register their own
+// collectors here to have them exposed alongside the platform metrics.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	// HttpRequestsTotal counts http requests by mux route template, method and
+	// status code.
+	HttpRequestsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "platform_http_requests_total",
+		Help: "Total number of http requests handled.",
+	}, []string{"server", "route", "method", "code"})
+
+	// HttpRequestDuration observes http request latency by mux route template
+	// and method.
+	HttpRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "platform_http_request_duration_seconds",
+		Help:    "Latency of http requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"server", "route", "method"})
+
+	// RunnerRunsTotal counts worker runs (including retry attempts) by runner.
+	RunnerRunsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "platform_runner_runs_total",
+		Help: "Total number of worker runs.",
+	}, []string{"runner"})
+
+	// RunnerRunErrorsTotal counts worker runs that returned an error by runner.
+	RunnerRunErrorsTotal = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "platform_runner_run_errors_total",
+		Help: "Total number of worker runs that returned an error.",
+	}, []string{"runner"})
+
+	// RunnerRunDuration observes how long worker runs take by runner.
+	RunnerRunDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "platform_runner_run_duration_seconds",
+		Help:    "Duration of worker runs.",
+		Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 900},
+	}, []string{"runner"})
+
+	// RunnerActiveWorkers tracks the number of workers that have not been
+	// parked by runner.
+	RunnerActiveWorkers = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "platform_runner_active_workers",
+		Help: "Number of workers that have not been parked.",
+	}, []string{"runner"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
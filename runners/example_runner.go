@@ -1,12 +1,12 @@
 package runners
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/derezzolution/platform/service"
-	"github.com/google/uuid"
 )
 
 // ExampleRunner performs a unit of work at some periodicity. For a quick
@@ -65,18 +65,23 @@ func NewExampleRunner(s *service.Service, id string) *ExampleRunner {
 }
 
 func (r *ExampleRunner) StartNewWorker() {
-	r.runner.StartNewWorker(r.work)
+	r.runner.StartNewWorkerContext(r.work)
 }
 
-func (r *ExampleRunner) work() error {
+func (r *ExampleRunner) work(ctx context.Context) error {
 	// r.Lock()
 	// defer r.Unlock()
 
-	runID := uuid.New().String()
+	// The context logger is tagged with the runner, worker and run id.
+	logger := service.LoggerFromContext(ctx)
 	busyWork := (int)(5 * rand.Float64())
-	r.runner.Logf("starting busy work which will run for %d seconds with id %s",
-		busyWork, runID)
+	logger.Info("starting busy work", "seconds", busyWork)
 
-	time.Sleep(time.Duration(busyWork) * time.Second)
-	return nil
+	// Bail out of the busy work early if the runner is stopping.
+	select {
+	case <-time.After(time.Duration(busyWork) * time.Second):
+		return nil
+	case <-ctx.Done():
+		return r.runner.Errorf("busy work aborted: %s", ctx.Err())
+	}
 }
@@ -1,24 +1,62 @@
 package service
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"runtime/debug"
+	"strings"
 
 	"github.com/derezzolution/platform/config"
 )
 
 var buildInfo = flag.Bool("build-info", false, "prints the build info from debug runtime")
 
+var configDir = flag.String("config-dir", "",
+	"reads configuration files from this directory only (instead of the default search path)")
+
+var configSchema = flag.Bool("config-schema", false,
+	"prints a json schema describing the configuration files (for editor completion and linting)")
+
+var configProvenance = flag.Bool("config-provenance", false,
+	"prints which file or environment variable supplied each configuration key")
+
 var doesShowTimestamp = flag.Bool("show-timestamp", true,
 	"shows or hides the timestamp in logs (useful when being invoked from systemd)")
 
-var property = flag.String("property", "",
-	"reads a property from the json configuration as a function of GO_ENV")
+var dumpConfig = flag.Bool("dump-config", false,
+	"prints the effective (merged and redacted) configuration as json")
+
+var properties propertiesFlag
+
+var propertyFormat = flag.String("property-format", string(config.PropertyFormatRaw),
+	"output format for -property: raw, json or shell-export")
 
 var version = flag.Bool("version", false, "prints the service version information as json")
 
+func init() {
+	flag.Var(&properties, "property",
+		"reads a property (scalar, object or array) from the configuration as a function of GO_ENV (repeat the flag "+
+			"or separate query paths with commas to read several)")
+}
+
+// propertiesFlag collects query paths from repeated and/or comma separated -property flags.
+type propertiesFlag []string
+
+func (p *propertiesFlag) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *propertiesFlag) Set(value string) error {
+	for _, queryPath := range strings.Split(value, ",") {
+		if queryPath = strings.TrimSpace(queryPath); len(queryPath) > 0 {
+			*p = append(*p, queryPath)
+		}
+	}
+	return nil
+}
+
 type Flagger interface {
 	// Parse reads flags into the flagger struct. It should be invoked after flag.Parse() which is typically handled by
 	// the derezzolution platform service.
@@ -33,8 +71,14 @@ type Flags struct {
 	service *Service
 
 	BuildInfo         bool
+	ConfigDir         string
+	ConfigProvenance  bool
+	ConfigSchema      bool
 	DoesShowTimestamp bool
-	Property          string
+	DumpConfig        bool
+	Property          string // First of Properties (kept for compatibility)
+	Properties        []string
+	PropertyFormat    string
 	Version           bool
 }
 
@@ -47,16 +91,24 @@ func NewFlags(service *Service) *Flags {
 // derezzolution platform service.
 func (f *Flags) Parse() {
 	f.BuildInfo = *buildInfo
+	f.ConfigDir = *configDir
+	f.ConfigProvenance = *configProvenance
+	f.ConfigSchema = *configSchema
 	f.DoesShowTimestamp = *doesShowTimestamp
-	f.Property = *property
+	f.DumpConfig = *dumpConfig
+	f.Properties = properties
+	if len(f.Properties) > 0 {
+		f.Property = f.Properties[0]
+	}
+	f.PropertyFormat = *propertyFormat
 	f.Version = *version
 }
 
 // Run oneshot flags (flags that terminate and don't agument service) that are specific to platform.
 func (f *Flags) Run() {
 	if f.BuildInfo {
-		build, err := debug.ReadBuildInfo()
-		if err {
+		build, ok := debug.ReadBuildInfo()
+		if !ok {
 			fmt.Println("unable to read build info from debug runtime")
 			os.Exit(1)
 		}
@@ -74,27 +126,76 @@ func (f *Flags) Run() {
 	}
 }
 
+// RunConfigSchema prints the json schema for the platform config and additional configurers when -config-schema is set.
+// Unlike the other oneshot flags, it doesn't depend on configuration files so it should run before they're loaded.
+func (f *Flags) RunConfigSchema(configurers ...config.Configurer) {
+	if !f.ConfigSchema {
+		return
+	}
+	allConfigurers := append([]config.Configurer{&config.Config{}}, configurers...)
+	b, err := json.MarshalIndent(config.Schema(allConfigurers...), "", "    ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(string(b))
+	os.Exit(0)
+}
+
 // RunWithConfigurer runs oneshot flags (flags that terminate and don't agument service) that are specific to platform
 // and have dependencies on an additional configurer.
 func (f *Flags) RunWithConfigurer(configurer config.Configurer) {
+	f.RunWithConfigurers(configurer)
+}
+
+// RunWithConfigurers runs oneshot flags (flags that terminate and don't agument service) that are specific to platform
+// and have dependencies on additional configurers. Properties are read from the platform config first and then each
+// configurer in order.
+func (f *Flags) RunWithConfigurers(configurers ...config.Configurer) {
+	allConfigurers := append([]config.Configurer{f.service.Config}, configurers...)
+	if f.DumpConfig {
+		merged, err := config.MergedRedactedMap(allConfigurers...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		b, err := json.MarshalIndent(merged, "", "    ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(string(b))
+		os.Exit(0)
+	}
+	if f.ConfigProvenance {
+		provenance, err := config.Provenance(config.DefaultLoadOptions, allConfigurers...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(config.FormatProvenance(provenance))
+		os.Exit(0)
+	}
 	if f.HasProperty() {
-		// Platform config takes precedence.
-		value, err := f.service.Config.ReadProperty(f.Property)
-		if err != nil && configurer == nil {
-			fmt.Println(value)
+		format, err := config.ParsePropertyFormat(f.PropertyFormat)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-		if err == nil {
-			fmt.Println(value)
-			os.Exit(0)
+
+		// Platform config takes precedence.
+		values, err := config.ReadConfigProperties(f.Properties, allConfigurers...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
-		// Additional config read afer platform config.
-		value, err = configurer.ReadProperty(f.Property)
-		fmt.Println(value)
+		output, err := config.FormatProperties(f.Properties, values, format)
 		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
+		fmt.Println(output)
 		os.Exit(0)
 	}
 }
@@ -109,5 +210,5 @@ func (f *Flags) RunWithFlagger(flagger Flagger) {
 
 // HasProperty returns whether we're attempting to read a property from the config.
 func (f *Flags) HasProperty() bool {
-	return len(f.Property) > 0
+	return len(f.Properties) > 0
 }
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/derezzolution/platform/config"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// newLogFile creates a rotating log file writer as a function of the platform
+// configuration. The file is opened lazily on first write.
+func newLogFile(c *config.Config) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   c.LogFile,
+		MaxSize:    c.LogMaxSize,
+		MaxAge:     c.LogMaxAge,
+		MaxBackups: c.LogMaxBackups,
+		LocalTime:  true,
+		Compress:   c.LogCompress,
+	}
+}
+
+// watchLogFile closes the log file on SIGHUP so it's reopened (at the same
+// path) on the next write. This keeps us compatible with external tools like
+// logrotate that move the file out from under us. If rotateHours is positive,
+// the log file is also rotated on that interval.
+func watchLogFile(logFile *lumberjack.Logger, rotateHours int) {
+	hangupChannel := make(chan os.Signal, 1)
+	signal.Notify(hangupChannel, syscall.SIGHUP)
+
+	var rotateChannel <-chan time.Time
+	if rotateHours > 0 {
+		rotateChannel = time.NewTicker(time.Duration(rotateHours) * time.Hour).C
+	}
+
+	for {
+		select {
+		case <-hangupChannel:
+			if err := logFile.Close(); err != nil {
+				log.Printf("error: could not reopen log file: %s", err)
+			}
+		case <-rotateChannel:
+			if err := logFile.Rotate(); err != nil {
+				log.Printf("error: could not rotate log file: %s", err)
+			}
+		}
+	}
+}
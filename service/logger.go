@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/derezzolution/platform/config"
+)
+
+type loggerContextKey struct{}
+
+// NewLogger creates a structured logger as a function of the platform
+// configuration. Output is json when LogFormat is "json" (text otherwise) and
+// messages below level are dropped.
+func NewLogger(w io.Writer, c *config.Config, level slog.Leveler, doesShowTimestamp bool) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if !doesShowTimestamp {
+		options.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}
+	}
+
+	if c.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+// logLevel returns the level to log at, debug messages are only written when
+// VerboseLogging is enabled.
+func logLevel(c *config.Config) slog.Level {
+	if c.VerboseLogging {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// LoggerFromContext returns the logger attached to a worker's context (tagged
+// with the runner name, worker id and run id) or the default logger if there
+// isn't one.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// ContextWithLogger attaches a logger to the context.
+func ContextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// configureLogger installs the service logger as the default logger, which
+// also routes the standard library log package through it. Logs are written to
+// the configured LogFile (with rotation) when set, otherwise to stderr.
+func (s *Service) configureLogger() {
+	var w io.Writer = os.Stderr
+	if len(s.Config.LogFile) > 0 {
+		logFile := newLogFile(s.Config)
+		go watchLogFile(logFile, s.Config.LogRotateHours)
+		w = logFile
+	}
+
+	s.logLevel.Set(logLevel(s.Config))
+	s.Logger = NewLogger(w, s.Config, &s.logLevel, s.Flags.DoesShowTimestamp)
+	slog.SetDefault(s.Logger)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/derezzolution/platform/config"
+	"github.com/fsnotify/fsnotify"
+)
+
+// configWatchDebounce is how long we wait for config file changes to settle
+// (editors often write files in several steps) before reloading.
+const configWatchDebounce = 500 * time.Millisecond
+
+// CurrentConfig returns the most recently loaded platform configuration. Unlike
+// the Config field (which holds the configuration loaded at start up), this
+// reflects any reloads.
+func (s *Service) CurrentConfig() *config.Config {
+	return s.config.Load()
+}
+
+// AddReloadListener adds a callback invoked after configuration is reloaded.
+// It's called once for each configurer (the platform *config.Config and then
+// each additional configurer) with the old and new values. Listeners are only
+// notified when every configurer loaded and validated successfully.
+func (s *Service) AddReloadListener(listener func(old, new config.Configurer)) {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+	s.reloadListeners = append(s.reloadListeners, listener)
+}
+
+// ReloadConfig loads and validates fresh instances of all configurers. Only if
+// all of them succeed are they swapped in and reload listeners notified;
+// otherwise the current configuration is kept and the error is returned.
+// Fresh instances are zero values unless the configurer implements
+// config.ConfigurerFactory, so defaults set on a configurer before it's passed
+// to the service must be set in Load or NewConfigurer to survive a reload.
+func (s *Service) ReloadConfig() error {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	newConfig := &config.Config{}
+	err := newConfig.Load()
+	if err != nil {
+		return fmt.Errorf("could not reload platform configuration: %s", err)
+	}
+
+	newAdditionalConfigurers := make([]config.Configurer, len(s.additionalConfigurers))
+	for i, configurer := range s.additionalConfigurers {
+		newAdditionalConfigurers[i], err = newConfigurerLike(configurer)
+		if err != nil {
+			return fmt.Errorf("could not reload additional configuration %T: %s", configurer, err)
+		}
+		err = newAdditionalConfigurers[i].Load()
+		if err != nil {
+			return fmt.Errorf("could not reload additional configuration %T: %s", configurer, err)
+		}
+	}
+
+	oldConfig := s.config.Swap(newConfig)
+	s.logLevel.Set(logLevel(newConfig))
+	oldAdditionalConfigurers := s.additionalConfigurers
+	s.additionalConfigurers = newAdditionalConfigurers
+
+	for _, listener := range s.reloadListeners {
+		listener(oldConfig, newConfig)
+		for i := range newAdditionalConfigurers {
+			listener(oldAdditionalConfigurers[i], newAdditionalConfigurers[i])
+		}
+	}
+	return nil
+}
+
+// watchConfig reloads configuration on SIGHUP and, if doesWatchFiles is set,
+// whenever a configuration file in the directories of the loaded files
+// changes.
+func (s *Service) watchConfig(doesWatchFiles bool) {
+	hangupChannel := make(chan os.Signal, 1)
+	signal.Notify(hangupChannel, syscall.SIGHUP)
+
+	var fileEvents <-chan fsnotify.Event
+	var fileErrors <-chan error
+	if doesWatchFiles {
+		watcher, err := fsnotify.NewWatcher()
+		for _, dir := range configDirs(s.CurrentConfig().LoadedFiles()) {
+			if err == nil {
+				err = watcher.Add(dir)
+			}
+		}
+		if err != nil {
+			log.Printf("warning: could not watch configuration files: %s", err)
+			if watcher != nil {
+				watcher.Close()
+			}
+		} else {
+			fileEvents = watcher.Events
+			fileErrors = watcher.Errors
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-hangupChannel:
+			log.Printf("received SIGHUP, reloading configuration")
+			s.reloadConfigAndLog()
+		case event, ok := <-fileEvents:
+			if !ok {
+				fileEvents = nil
+				continue
+			}
+			if config.IsConfigFilename(event.Name) && !event.Has(fsnotify.Chmod) {
+				debounce = time.After(configWatchDebounce)
+			}
+		case err, ok := <-fileErrors:
+			// Errors must be drained or the watcher blocks and stops sending
+			// events. After an error (e.g. an event queue overflow) changes may
+			// have been missed, so reload to be safe.
+			if !ok {
+				fileErrors = nil
+				continue
+			}
+			log.Printf("warning: error watching configuration files: %s", err)
+			debounce = time.After(configWatchDebounce)
+		case <-debounce:
+			log.Printf("configuration files changed, reloading configuration")
+			s.reloadConfigAndLog()
+		}
+	}
+}
+
+func (s *Service) reloadConfigAndLog() {
+	err := s.ReloadConfig()
+	if err != nil {
+		log.Printf("error: %s (keeping current configuration)", err)
+		return
+	}
+	log.Printf("configuration reloaded")
+	s.CurrentConfig().LogSummary()
+}
+
+// configDirs returns the distinct directories of the files.
+func configDirs(files []string) []string {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// newConfigurerLike creates a fresh instance of the configurer to load. It's
+// created by the configurer's NewConfigurer method when it implements
+// config.ConfigurerFactory, otherwise it's a new zero value of the same type
+// (which must be a pointer to a struct).
+func newConfigurerLike(c config.Configurer) (config.Configurer, error) {
+	if factory, ok := c.(config.ConfigurerFactory); ok {
+		return factory.NewConfigurer(), nil
+	}
+
+	t := reflect.TypeOf(c)
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("configurer must be a pointer to a struct to be reloaded, got %T", c)
+	}
+	return reflect.New(t.Elem()).Interface().(config.Configurer), nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/derezzolution/platform/config"
+)
+
+type defaultsConfig struct {
+	config.Config
+	Timeout int `json:"timeout"`
+}
+
+type factoryConfig struct {
+	defaultsConfig
+}
+
+func (c *factoryConfig) NewConfigurer() config.Configurer {
+	return &factoryConfig{defaultsConfig{Timeout: 5}}
+}
+
+func TestNewConfigurerLike(t *testing.T) {
+	fresh, err := newConfigurerLike(&defaultsConfig{Timeout: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fresh.(*defaultsConfig).Timeout; got != 0 {
+		t.Errorf("got timeout %d for a zero value, want 0", got)
+	}
+
+	fresh, err = newConfigurerLike(&factoryConfig{defaultsConfig{Timeout: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fresh.(*factoryConfig).Timeout; got != 5 {
+		t.Errorf("got timeout %d from NewConfigurer, want 5", got)
+	}
+}
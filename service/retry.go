@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how a runner retries a worker run that returned an
+// error. Retries back off exponentially (with jitter) rather than waiting for
+// the regular cadence, so flaky dependencies aren't hammered.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts for a run, including the
+	// first. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the wait before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between retries (zero means uncapped).
+	MaxBackoff time.Duration
+
+	// Multiplier is applied to the backoff after each retry (defaults to 2).
+	Multiplier float64
+
+	// Jitter is the fraction (0 to 1) of each backoff that's randomized, e.g.
+	// 0.2 waits somewhere between 80% and 100% of the computed backoff.
+	Jitter float64
+}
+
+// RetryableError can be implemented by errors returned from workers to decide
+// whether a failed run should be retried. Errors that don't implement it are
+// always considered retryable.
+type RetryableError interface {
+	error
+	Retryable() bool
+}
+
+type retryableError struct {
+	err       error
+	retryable bool
+}
+
+func (e *retryableError) Error() string   { return e.err.Error() }
+func (e *retryableError) Unwrap() error   { return e.err }
+func (e *retryableError) Retryable() bool { return e.retryable }
+
+// Permanent marks an error as not retryable so the runner gives up on the run
+// right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err, retryable: false}
+}
+
+// IsRetryable reports whether an error returned from a worker should be
+// retried.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var retryableErr RetryableError
+	if errors.As(err, &retryableErr) {
+		return retryableErr.Retryable()
+	}
+	return true
+}
+
+// shouldRetry reports whether another attempt should follow the given
+// (1-based) attempt that failed with err.
+func (p *RetryPolicy) shouldRetry(attempt int, err error) bool {
+	return p != nil && attempt < p.MaxAttempts && IsRetryable(err)
+}
+
+// backoff returns how long to wait after the given (1-based) failed attempt.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if backoff >= float64(math.MaxInt64) {
+		// float64(math.MaxInt64) rounds up to 2^63, which overflows a Duration.
+		return time.Duration(math.MaxInt64)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"first", RetryPolicy{InitialBackoff: time.Second}, 1, time.Second},
+		{"doubles by default", RetryPolicy{InitialBackoff: time.Second}, 3, 4 * time.Second},
+		{"multiplier", RetryPolicy{InitialBackoff: time.Second, Multiplier: 3}, 3, 9 * time.Second},
+		{"capped", RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, 10, 5 * time.Second},
+		{"uncapped overflow", RetryPolicy{InitialBackoff: time.Second}, 35, time.Duration(math.MaxInt64)},
+		{"uncapped overflow with jitter", RetryPolicy{InitialBackoff: time.Second, Jitter: 0.5}, 100,
+			time.Duration(math.MaxInt64)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.policy.backoff(test.attempt); got != test.want {
+				t.Errorf("got backoff %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffJitter(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 10 * time.Second, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		got := policy.backoff(1)
+		if got < 8*time.Second || got > 10*time.Second {
+			t.Fatalf("got backoff %s, want between 8s and 10s", got)
+		}
+	}
+}
@@ -1,13 +1,16 @@
 package service
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"sync"
 	"time"
 
+	"github.com/derezzolution/platform/metrics"
 	"github.com/dustin/go-humanize"
+	"github.com/google/uuid"
 )
 
 // Runners control how workers are executed at a periodicity. For a usage
@@ -15,8 +18,12 @@ import (
 type Runner struct {
 	mutex      sync.Mutex
 	config     RunnerConfig
+	ctx        context.Context
+	cancel     context.CancelFunc
+	logger     *slog.Logger
 	isStopping bool
 	nWorkers   int
+	workers    []*WorkerStatus
 	wg         sync.WaitGroup
 }
 
@@ -39,6 +46,15 @@ type RunnerConfig struct {
 	// Name of the runner (used in logging).
 	Name string
 
+	// RetryPolicy (optional) retries failed worker runs with exponential
+	// backoff before falling back to the regular cadence.
+	RetryPolicy *RetryPolicy
+
+	// Schedule (optional) determines when each worker run starts, e.g. from
+	// NewCronSchedule or NewFixedRateSchedule. When set, WorkerSleepDuration
+	// is ignored.
+	Schedule Schedule
+
 	// WorkerSleepDuration is how much time a worker sleeps after a run, before
 	// it starts again.
 	WorkerSleepDuration time.Duration
@@ -46,16 +62,32 @@ type RunnerConfig struct {
 
 // NewRunner creates a new runner with clean-up behaviors.
 func NewRunner(service *Service, config RunnerConfig) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
 	r := &Runner{
 		config:     config,
+		ctx:        ctx,
+		cancel:     cancel,
+		logger:     service.logger().With("runner", config.Name),
 		isStopping: false,
 	}
 	service.installRunner(r)
 	return r
 }
 
+// StartNewWorker starts a worker that isn't context aware. It only notices the
+// runner stopping in between runs.
 func (r *Runner) StartNewWorker(worker func() error) {
-	r.countNewWorker()
+	r.StartNewWorkerContext(func(ctx context.Context) error {
+		return worker()
+	})
+}
+
+// StartNewWorkerContext starts a worker whose context is cancelled as soon as
+// the runner begins stopping. Workers should pass the context along to long
+// running operations (e.g. network requests) so they abort promptly instead of
+// holding up shutdown until MaximumCleanUpDuration.
+func (r *Runner) StartNewWorkerContext(worker func(ctx context.Context) error) {
+	w := r.countNewWorker()
 	go func() {
 		startDelay := 1 * time.Second
 		startDelay += r.config.InitDelayDuration
@@ -68,16 +100,30 @@ func (r *Runner) StartNewWorker(worker func() error) {
 
 		r.Logf("starting new worker %s",
 			humanize.Time(time.Now().Add(startDelay)))
-		time.Sleep(startDelay)
-		r.Logf("new worker started")
+		r.sleep(startDelay)
+		if !r.IsStopping() {
+			r.Logf("new worker started")
+		}
 
 		for {
-			err := r.run(worker)
-			if err != nil {
-				r.Logf("%s", err)
+			if r.config.Schedule != nil {
+				next := r.config.Schedule.Next(time.Now())
+				if next.IsZero() {
+					// A schedule with no next time would otherwise have the
+					// worker run back to back, so stop it instead.
+					r.logger.Error("schedule has no next run time, stopping worker",
+						"worker", w.ID)
+					r.parkWorker(w)
+					return
+				}
+				r.setWorkerState(w, WorkerStateSleeping)
+				r.sleep(time.Until(next))
+			}
+			if !r.IsStopping() {
+				r.runWithRetry(w, worker)
 			}
-			if r.isStopping {
-				r.parkWorker()
+			if r.IsStopping() {
+				r.parkWorker(w)
 
 				// Sleep the worker to the maximum clean up duration, thereby
 				// effectively parking this worker forever. We do this because
@@ -85,7 +131,10 @@ func (r *Runner) StartNewWorker(worker func() error) {
 				time.Sleep(r.config.MaximumCleanUpDuration)
 				break
 			}
-			time.Sleep(r.config.WorkerSleepDuration)
+			if r.config.Schedule == nil {
+				r.setWorkerState(w, WorkerStateSleeping)
+				r.sleep(r.config.WorkerSleepDuration)
+			}
 		}
 	}()
 }
@@ -96,11 +145,16 @@ func (r *Runner) Stop() error {
 		err := fmt.Errorf("runner is already in the process of stopping, " +
 			"stop request ignored")
 		r.Logf(err.Error())
+		r.mutex.Unlock()
 		return err
 	}
 	r.isStopping = true
 	r.mutex.Unlock()
 
+	// Cancel the worker context so context aware workers abort whatever
+	// they're in the middle of rather than running out the clean up clock.
+	r.cancel()
+
 	r.Logf("stopping runner, waiting for %d workers to leave waitgroup",
 		r.nWorkers)
 	c := make(chan struct{}, 1)
@@ -122,6 +176,8 @@ func (r *Runner) Stop() error {
 }
 
 func (r *Runner) IsStopping() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	return r.isStopping
 }
 
@@ -129,9 +185,17 @@ func (r *Runner) FullName() string {
 	return fmt.Sprintf("%s-runner", r.config.Name)
 }
 
+// Logger returns the runner's structured logger (tagged with the runner name).
+func (r *Runner) Logger() *slog.Logger {
+	return r.logger
+}
+
 func (r *Runner) Logf(pattern string, args ...interface{}) {
-	log.Printf("%s: "+pattern,
-		append([]interface{}{r.FullName()}, args...)...)
+	r.logger.Info(fmt.Sprintf(pattern, args...))
+}
+
+func (r *Runner) Debugf(pattern string, args ...interface{}) {
+	r.logger.Debug(fmt.Sprintf(pattern, args...))
 }
 
 func (r *Runner) Errorf(pattern string, args ...interface{}) error {
@@ -139,23 +203,80 @@ func (r *Runner) Errorf(pattern string, args ...interface{}) error {
 		append([]interface{}{r.FullName()}, args...)...)
 }
 
-func (r *Runner) countNewWorker() {
+func (r *Runner) countNewWorker() *WorkerStatus {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 	r.nWorkers++
+	metrics.RunnerActiveWorkers.WithLabelValues(r.config.Name).Inc()
+	w := &WorkerStatus{
+		ID:    len(r.workers),
+		State: WorkerStateWaitingForInitDelay,
+	}
+	r.workers = append(r.workers, w)
+	return w
 }
 
-func (r *Runner) parkWorker() {
+func (r *Runner) parkWorker(w *WorkerStatus) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
+	w.State = WorkerStateParked
 	r.nWorkers--
+	metrics.RunnerActiveWorkers.WithLabelValues(r.config.Name).Dec()
 	if r.nWorkers > 0 {
 		r.Logf("worker stopped, %d remaining", r.nWorkers)
 	}
 }
 
-func (r *Runner) run(worker func() error) error {
+// run runs the worker once with the given logger (tagged with the worker and
+// run ids) in its context.
+func (r *Runner) run(w *WorkerStatus, logger *slog.Logger, worker func(ctx context.Context) error) error {
 	r.wg.Add(1)
 	defer r.wg.Done()
-	return worker()
+	r.recordRunStart(w)
+	start := time.Now()
+	err := worker(ContextWithLogger(r.ctx, logger))
+	duration := time.Since(start)
+	logger.Debug("run finished", "duration", duration, "error", err)
+	metrics.RunnerRunDuration.WithLabelValues(r.config.Name).Observe(duration.Seconds())
+	metrics.RunnerRunsTotal.WithLabelValues(r.config.Name).Inc()
+	if err != nil {
+		metrics.RunnerRunErrorsTotal.WithLabelValues(r.config.Name).Inc()
+	}
+	r.recordRunFinish(w, err)
+	return err
+}
+
+// runWithRetry runs the worker, retrying failures as directed by the runner's
+// retry policy. Retries are abandoned once the runner begins stopping.
+func (r *Runner) runWithRetry(w *WorkerStatus, worker func(ctx context.Context) error) {
+	workerLogger := r.logger.With("worker", w.ID)
+	for attempt := 1; ; attempt++ {
+		logger := workerLogger.With("run", uuid.New().String())
+		err := r.run(w, logger, worker)
+		if err == nil {
+			return
+		}
+		if !r.config.RetryPolicy.shouldRetry(attempt, err) || r.IsStopping() {
+			logger.Error(err.Error())
+			return
+		}
+
+		backoff := r.config.RetryPolicy.backoff(attempt)
+		logger.Warn(fmt.Sprintf("attempt %d of %d failed, retrying in %s: %s", attempt,
+			r.config.RetryPolicy.MaxAttempts, backoff, err))
+		r.setWorkerState(w, WorkerStateSleeping)
+		r.sleep(backoff)
+		if r.IsStopping() {
+			return
+		}
+	}
+}
+
+// sleep pauses the worker for the given duration, waking early if the runner
+// begins stopping.
+func (r *Runner) sleep(d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.ctx.Done():
+	}
 }
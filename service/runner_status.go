@@ -0,0 +1,102 @@
+package service
+
+import (
+	"time"
+)
+
+// WorkerState describes what a runner's worker is currently doing.
+type WorkerState string
+
+const (
+	WorkerStateWaitingForInitDelay WorkerState = "waiting-for-init-delay"
+	WorkerStateRunning             WorkerState = "running"
+	WorkerStateSleeping            WorkerState = "sleeping"
+	WorkerStateParked              WorkerState = "parked"
+)
+
+// WorkerStatus is a snapshot of a single worker within a runner.
+type WorkerStatus struct {
+	// ID identifies the worker within its runner (in order of creation).
+	ID int `json:"id"`
+
+	State WorkerState `json:"state"`
+
+	// LastStart and LastFinish are the start and finish of the most recent
+	// run (LastFinish is before LastStart while a run is in progress).
+	LastStart    time.Time     `json:"lastStart"`
+	LastFinish   time.Time     `json:"lastFinish"`
+	LastDuration time.Duration `json:"lastDuration"`
+
+	// LastError is the error from the most recent failed run (it's kept after
+	// subsequent successful runs, see ConsecutiveFailures).
+	LastError string `json:"lastError,omitempty"`
+
+	// ConsecutiveFailures is the number of failed runs since the last
+	// successful one. Retry attempts count as runs.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+	TotalFailures       int `json:"totalFailures"`
+	TotalRuns           int `json:"totalRuns"`
+}
+
+// RunnerStatus is a snapshot of a runner and all of its workers.
+type RunnerStatus struct {
+	Name       string         `json:"name"`
+	IsStopping bool           `json:"isStopping"`
+	NWorkers   int            `json:"nWorkers"` // Workers that have not been parked
+	Workers    []WorkerStatus `json:"workers"`
+}
+
+// Status returns a point-in-time snapshot of the runner and its workers.
+func (r *Runner) Status() RunnerStatus {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	status := RunnerStatus{
+		Name:       r.config.Name,
+		IsStopping: r.isStopping,
+		NWorkers:   r.nWorkers,
+		Workers:    make([]WorkerStatus, len(r.workers)),
+	}
+	for i, w := range r.workers {
+		status.Workers[i] = *w
+	}
+	return status
+}
+
+// RunnerStatuses returns a status snapshot for each installed runner, in order
+// of creation.
+func (s *Service) RunnerStatuses() []RunnerStatus {
+	statuses := make([]RunnerStatus, len(s.runners))
+	for i, r := range s.runners {
+		statuses[i] = r.Status()
+	}
+	return statuses
+}
+
+func (r *Runner) setWorkerState(w *WorkerStatus, state WorkerState) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	w.State = state
+}
+
+func (r *Runner) recordRunStart(w *WorkerStatus) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	w.State = WorkerStateRunning
+	w.LastStart = time.Now()
+}
+
+func (r *Runner) recordRunFinish(w *WorkerStatus, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	w.LastFinish = time.Now()
+	w.LastDuration = w.LastFinish.Sub(w.LastStart)
+	w.TotalRuns++
+	if err != nil {
+		w.LastError = err.Error()
+		w.ConsecutiveFailures++
+		w.TotalFailures++
+	} else {
+		w.ConsecutiveFailures = 0
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRunWithRetryLogsWorkerAndRun(t *testing.T) {
+	var buffer bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &Runner{
+		config: RunnerConfig{
+			Name:        "test",
+			RetryPolicy: &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		},
+		ctx:    ctx,
+		cancel: cancel,
+		logger: slog.New(slog.NewJSONHandler(&buffer, nil)),
+	}
+	w := r.countNewWorker()
+	r.runWithRetry(w, func(ctx context.Context) error {
+		LoggerFromContext(ctx).Info("working")
+		return errors.New("failed")
+	})
+
+	var lines []map[string]interface{}
+	for _, b := range bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n")) {
+		var line map[string]interface{}
+		err := json.Unmarshal(b, &line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+
+	// Each run logs "working" and is followed by its retry or failure line,
+	// which has to carry the same worker and run ids.
+	wantLevels := []string{"INFO", "WARN", "INFO", "ERROR"}
+	if len(lines) != len(wantLevels) {
+		t.Fatalf("got %d log lines, want %d: %s", len(lines), len(wantLevels), buffer.String())
+	}
+	for i, line := range lines {
+		if line["level"] != wantLevels[i] {
+			t.Errorf("line %d: got level %v, want %s", i, line["level"], wantLevels[i])
+		}
+		if line["worker"] != float64(w.ID) {
+			t.Errorf("line %d: got worker %v, want %d", i, line["worker"], w.ID)
+		}
+		if run := lines[i-i%2]["run"]; line["run"] != run || run == nil {
+			t.Errorf("line %d: got run %v, want %v", i, line["run"], run)
+		}
+	}
+	if lines[0]["run"] == lines[2]["run"] {
+		t.Errorf("got the same run id %v for both attempts", lines[0]["run"])
+	}
+}
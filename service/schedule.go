@@ -0,0 +1,65 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule determines when a worker should start its next run. When a runner
+// has a schedule, it's used in place of WorkerSleepDuration.
+type Schedule interface {
+	// Next returns the next time a run should start, later than the given time.
+	Next(t time.Time) time.Time
+}
+
+// cronParser accepts standard 5-field cron expressions, 6-field expressions
+// with a leading seconds field, descriptors (e.g. "@hourly") and a
+// "CRON_TZ=<zone>" or "TZ=<zone>" prefix.
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow |
+	cron.Descriptor)
+
+// NewCronSchedule parses a cron expression (e.g. "0 2 * * 1-5" for weekdays at
+// 02:00) into a schedule evaluated in the given location. If location is nil,
+// the local timezone is used. A timezone prefix in the expression itself takes
+// precedence over location.
+func NewCronSchedule(expression string, location *time.Location) (Schedule, error) {
+	spec := strings.TrimSpace(expression)
+	if location != nil && !strings.HasPrefix(spec, "TZ=") && !strings.HasPrefix(spec, "CRON_TZ=") {
+		spec = fmt.Sprintf("CRON_TZ=%s %s", location.String(), spec)
+	}
+
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse cron expression %q: %s", expression, err)
+	}
+
+	// Expressions like "0 0 30 2 *" (February 30th) parse but never fire, in
+	// which case Next returns the zero time.
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches a time", expression)
+	}
+	return schedule, nil
+}
+
+// fixedRateSchedule runs at every multiple of interval (measured from the zero
+// time) so runs don't drift by however long the previous run took.
+type fixedRateSchedule struct {
+	interval time.Duration
+}
+
+// NewFixedRateSchedule creates a schedule that starts a run every interval on
+// interval boundaries (e.g. an hourly interval runs on the hour). Runs that are
+// still in progress at a boundary cause that boundary to be skipped.
+func NewFixedRateSchedule(interval time.Duration) (Schedule, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("fixed rate interval must be positive, got %s", interval)
+	}
+	return &fixedRateSchedule{interval: interval}, nil
+}
+
+func (s *fixedRateSchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedRateScheduleNext(t *testing.T) {
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		interval time.Duration
+		now      time.Time
+		want     time.Time
+	}{
+		{"on boundary", time.Hour, base, base.Add(time.Hour)},
+		{"mid interval", time.Hour, base.Add(20 * time.Minute), base.Add(time.Hour)},
+		{"just before boundary", time.Hour, base.Add(time.Hour - time.Nanosecond), base.Add(time.Hour)},
+		{"minutes", 15 * time.Minute, base.Add(31 * time.Minute), base.Add(45 * time.Minute)},
+		{"seconds", 10 * time.Second, base.Add(time.Minute + 3*time.Second), base.Add(time.Minute + 10*time.Second)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := NewFixedRateSchedule(test.interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(test.now); !got.Equal(test.want) {
+				t.Errorf("got next %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewFixedRateScheduleRejectsNonPositive(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := NewFixedRateSchedule(interval); err == nil {
+			t.Errorf("expected an error for interval %s", interval)
+		}
+	}
+}
+
+func TestNewCronScheduleTimezone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %s", err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %s", err)
+	}
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		expression string
+		location   *time.Location
+		want       time.Time
+	}{
+		{"location", "0 2 * * *", newYork, time.Date(2024, 3, 2, 2, 0, 0, 0, newYork)},
+		{"CRON_TZ prefix overrides location", "CRON_TZ=Asia/Tokyo 0 2 * * *", newYork,
+			time.Date(2024, 3, 2, 2, 0, 0, 0, tokyo)},
+		{"TZ prefix overrides location", "TZ=Asia/Tokyo 0 2 * * *", newYork,
+			time.Date(2024, 3, 2, 2, 0, 0, 0, tokyo)},
+		{"prefix without location", "CRON_TZ=America/New_York 0 2 * * *", nil,
+			time.Date(2024, 3, 2, 2, 0, 0, 0, newYork)},
+		{"surrounding whitespace", "  0 2 * * *  ", newYork, time.Date(2024, 3, 2, 2, 0, 0, 0, newYork)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := NewCronSchedule(test.expression, test.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(now); !got.Equal(test.want) {
+				t.Errorf("got next %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewCronScheduleErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+	}{
+		{"invalid", "not a cron expression"},
+		{"never matches", "0 0 30 2 *"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewCronSchedule(test.expression, time.UTC); err == nil {
+				t.Errorf("expected an error for %q", test.expression)
+			}
+		})
+	}
+}
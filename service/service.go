@@ -4,8 +4,12 @@ import (
 	"embed"
 	"flag"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/derezzolution/platform/config"
@@ -16,21 +20,91 @@ import (
 type Service struct {
 	Config  *config.Config
 	Flags   *Flags
+	Logger  *slog.Logger
 	Version *Version
 
 	runners            []*Runner
 	interruptListeners []func()
+	isShuttingDown     atomic.Bool
+
+	config                atomic.Pointer[config.Config]
+	additionalConfigurers []config.Configurer
+	logLevel              slog.LevelVar
+	reloadListeners       []func(old, new config.Configurer)
+	reloadMutex           sync.Mutex
 }
 
+// NoConfigEnvPrefix can be set as ServiceOptions.ConfigEnvPrefix to override configuration fields from environment
+// variables without a prefix (e.g. HTTP_PORT).
+const NoConfigEnvPrefix = "-"
+
 // ServiceOptions allow additional service configurability with the NewServiceWithOptions constructor.
 type ServiceOptions struct {
-	// AdditionalConfigurer can be used for additional configurers (configurations from services that use platform). It
-	// could make sense for this to be an array of configurers.
+	// AdditionalConfigurer can be used for an additional configurer (configuration from a service that uses platform).
+	// It's loaded before AdditionalConfigurers.
 	AdditionalConfigurer config.Configurer
 
-	// AdditionalFlagger can be used for additional flaggers (flag structs from services that use platform). It could
-	// make sense for this to be an array of flaggers.
+	// AdditionalConfigurers can be used for additional configurers (e.g. one per library a service composes). They're
+	// loaded and validated in order and -property lookups search them in order after the platform configuration.
+	AdditionalConfigurers []config.Configurer
+
+	// AdditionalFlagger can be used for an additional flagger (flag struct from a service that uses platform). It's
+	// parsed and run before AdditionalFlaggers.
 	AdditionalFlagger Flagger
+
+	// AdditionalFlaggers can be used for additional flaggers, parsed and run in order.
+	AdditionalFlaggers []Flagger
+
+	// ConfigEnvPrefix overrides the prefix of environment variables that override configuration fields (defaults to
+	// "PLATFORM", e.g. PLATFORM_HTTP_PORT). Since an empty prefix means the default, use NoConfigEnvPrefix for none.
+	ConfigEnvPrefix string
+
+	// ConfigLayers is an explicit, ordered list of environments to layer (the last taking precedence). When empty,
+	// layers are resolved from the "extends" key in each configuration file.
+	ConfigLayers []string
+
+	// ConfigSecretResolvers add (or override) resolvers for "${<scheme>:<reference>}" secret references in
+	// configuration strings, keyed by scheme ("env" and "file" are built in).
+	ConfigSecretResolvers map[string]config.SecretResolver
+
+	// ConfigStrict controls whether unknown keys in configuration files are rejected (by default, only in production).
+	ConfigStrict config.StrictMode
+
+	// ConfigSearchDirs are the directories searched (in order) for configuration files. Defaults to the executable's
+	// directory, /etc/<service> and then the working directory. The -config-dir flag takes precedence.
+	ConfigSearchDirs []string
+
+	// ConfigWatchFiles reloads configuration whenever a configuration file changes (configuration is always reloaded
+	// on SIGHUP).
+	ConfigWatchFiles bool
+}
+
+// configurers returns all additional configurers in the order they should be loaded.
+func (o *ServiceOptions) configurers() []config.Configurer {
+	var configurers []config.Configurer
+	if o.AdditionalConfigurer != nil {
+		configurers = append(configurers, o.AdditionalConfigurer)
+	}
+	for _, configurer := range o.AdditionalConfigurers {
+		if configurer != nil {
+			configurers = append(configurers, configurer)
+		}
+	}
+	return configurers
+}
+
+// flaggers returns all additional flaggers in the order they should be parsed and run.
+func (o *ServiceOptions) flaggers() []Flagger {
+	var flaggers []Flagger
+	if o.AdditionalFlagger != nil {
+		flaggers = append(flaggers, o.AdditionalFlagger)
+	}
+	for _, flagger := range o.AdditionalFlaggers {
+		if flagger != nil {
+			flaggers = append(flaggers, flagger)
+		}
+	}
+	return flaggers
 }
 
 // NewService creates a new service by initializing foundational harness.
@@ -46,8 +120,9 @@ func NewServiceWithOptions(packageFS *embed.FS, options *ServiceOptions) *Servic
 	// Parse flags
 	flag.Parse()
 	s.Flags.Parse()
-	if options.AdditionalFlagger != nil {
-		options.AdditionalFlagger.Parse()
+	flaggers := options.flaggers()
+	for _, flagger := range flaggers {
+		flagger.Parse()
 	}
 
 	// Configure logger flags.
@@ -65,6 +140,29 @@ func NewServiceWithOptions(packageFS *embed.FS, options *ServiceOptions) *Servic
 	s.Version = v
 
 	// Load config.
+	if options.ConfigEnvPrefix == NoConfigEnvPrefix {
+		config.DefaultLoadOptions.EnvPrefix = ""
+	} else if len(options.ConfigEnvPrefix) > 0 {
+		config.DefaultLoadOptions.EnvPrefix = options.ConfigEnvPrefix
+	}
+	if len(options.ConfigLayers) > 0 {
+		config.DefaultLoadOptions.Layers = options.ConfigLayers
+	}
+	if len(options.ConfigSecretResolvers) > 0 {
+		config.DefaultLoadOptions.SecretResolvers = options.ConfigSecretResolvers
+	}
+	switch {
+	case len(s.Flags.ConfigDir) > 0:
+		config.DefaultLoadOptions.SearchDirs = []string{s.Flags.ConfigDir}
+	case len(options.ConfigSearchDirs) > 0:
+		config.DefaultLoadOptions.SearchDirs = options.ConfigSearchDirs
+	default:
+		config.DefaultLoadOptions.SearchDirs = config.DefaultSearchDirs(filepath.Base(os.Args[0]))
+	}
+	config.DefaultLoadOptions.Strict = options.ConfigStrict
+	configurers := options.configurers()
+	config.DefaultLoadOptions.SharedConfigurers = append([]config.Configurer{&config.Config{}}, configurers...)
+	s.Flags.RunConfigSchema(configurers...)
 	c := &config.Config{}
 	err = c.Load()
 	if err != nil {
@@ -74,26 +172,35 @@ func NewServiceWithOptions(packageFS *embed.FS, options *ServiceOptions) *Servic
 		os.Exit(1)
 	}
 	s.Config = c
+	s.config.Store(c)
 
-	// Load additional config.
-	if options.AdditionalConfigurer != nil {
-		err = options.AdditionalConfigurer.Load()
+	// Load additional configs.
+	for _, configurer := range configurers {
+		err = configurer.Load()
 		if err != nil {
 			if !s.Flags.HasProperty() {
-				log.Printf("error: could not load additional configuration: %s", err)
+				log.Printf("error: could not load additional configuration %T: %s", configurer, err)
 			}
 			os.Exit(1)
 		}
 	}
+	s.additionalConfigurers = configurers
 
 	s.Flags.Run()
-	s.Flags.RunWithConfigurer(options.AdditionalConfigurer)
-	s.Flags.RunWithFlagger(options.AdditionalFlagger)
+	s.Flags.RunWithConfigurers(configurers...)
+	for _, flagger := range flaggers {
+		s.Flags.RunWithFlagger(flagger)
+	}
+
+	// Switch over to structured logging now that the config is loaded.
+	s.configureLogger()
 
 	log.Printf("derezzolution platform Copyright © 2024 derezz.com. All rights reserved.")
 	s.Version.LogSummary()
 	s.Config.LogSummary()
 
+	go s.watchConfig(options.ConfigWatchFiles)
+
 	return s
 }
 
@@ -104,6 +211,12 @@ func (s *Service) AddInterruptListener(listener func()) {
 	s.interruptListeners = append(s.interruptListeners, listener)
 }
 
+// IsReady returns whether the service is ready to take on new work. This turns
+// false as soon as an OS signal triggering service termination is received.
+func (s *Service) IsReady() bool {
+	return !s.isShuttingDown.Load()
+}
+
 // Run the service with a blocking busy-wait watching for OS Signals.
 func (s *Service) Run() {
 	s.RunWithCleanUp(func() error {
@@ -137,6 +250,7 @@ func (s *Service) RunWithCleanUp(cleanUpFunc func() error) {
 	signalChannel := make(chan os.Signal, 2)
 	signal.Notify(signalChannel, os.Interrupt, syscall.SIGTERM)
 	sig := <-signalChannel
+	s.isShuttingDown.Store(true)
 	log.Printf("received %s signal from OS, alerting %d interrupt listener(s) "+
 		"and stopping %d runner(s)", sig.String(), len(s.interruptListeners),
 		len(s.runners))
@@ -174,6 +288,15 @@ func (s *Service) RunWithCleanUp(cleanUpFunc func() error) {
 	os.Exit(0)
 }
 
+// logger returns the service logger, falling back to the default logger if the
+// service hasn't configured one (e.g. a zero Service).
+func (s *Service) logger() *slog.Logger {
+	if s.Logger == nil {
+		return slog.Default()
+	}
+	return s.Logger
+}
+
 func (s *Service) installRunner(runner *Runner) {
 	s.runners = append(s.runners, runner)
 }